@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/August26/proxycheck-go/internal/judge"
+	"github.com/August26/proxycheck-go/internal/logging"
+)
+
+// runJudge implements "proxycheck-go judge": a self-hosted echo endpoint
+// to point --judge-url at instead of httpbin.org.
+func runJudge(args []string) {
+	fs := flag.NewFlagSet("judge", flag.ExitOnError)
+	listen := fs.String("listen", ":8080", "plain HTTP listen address (empty to disable)")
+	tlsListen := fs.String("tls-listen", "", "HTTPS listen address (empty to disable)")
+	certFile := fs.String("cert", "", "PEM certificate for HTTPS (self-signed if empty)")
+	keyFile := fs.String("key", "", "PEM private key for HTTPS")
+	hosts := fs.String("hosts", "localhost,127.0.0.1", "comma-separated names/IPs for the self-signed certificate")
+	certOut := fs.String("cert-out", "", "write the self-signed certificate (PEM) here, for --judge-ca")
+	ipv6Listen := fs.String("ipv6-listen", "", "plain HTTP listen address on an IPv6 address, e.g. [2001:db8::1]:8080, for --ipv6-url (empty to disable)")
+	dnsListen := fs.String("dns-listen", "", "UDP listen address of the DNS stand-in for --dns-zone (empty to disable)")
+	dnsZone := fs.String("dns-zone", "", "zone delegated to this host, answered by the DNS stand-in for DNS leak tests")
+	dnsAnswer := fs.String("dns-answer", "", "IP returned for names in --dns-zone (default: no records)")
+	verbose := fs.Bool("verbose", false, "enable debug logs")
+	fs.Parse(args)
+
+	log := logging.NewLogger(*verbose)
+
+	if *listen == "" && *tlsListen == "" && *ipv6Listen == "" && *dnsListen == "" {
+		fmt.Fprintln(os.Stderr, "judge: at least one of --listen, --tls-listen, --ipv6-listen or --dns-listen is required")
+		os.Exit(1)
+	}
+
+	errCh := make(chan error, 4)
+
+	if *listen != "" {
+		ln, err := net.Listen("tcp", *listen)
+		if err != nil {
+			log.Error("judge listen failed", "err", err, "addr", *listen)
+			os.Exit(1)
+		}
+		log.Info("judge listening", "addr", ln.Addr().String(), "tls", false)
+		go func() { errCh <- judge.NewServer().Serve(judge.NewListener(ln, nil)) }()
+	}
+
+	if *tlsListen != "" {
+		cert, err := loadJudgeCert(*certFile, *keyFile, *hosts, *certOut)
+		if err != nil {
+			log.Error("judge certificate setup failed", "err", err)
+			os.Exit(1)
+		}
+		ln, err := net.Listen("tcp", *tlsListen)
+		if err != nil {
+			log.Error("judge listen failed", "err", err, "addr", *tlsListen)
+			os.Exit(1)
+		}
+		log.Info("judge listening", "addr", ln.Addr().String(), "tls", true)
+		tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+		go func() { errCh <- judge.NewServer().Serve(judge.NewListener(ln, tlsConfig)) }()
+	}
+
+	if *ipv6Listen != "" {
+		// tcp6: never dual-stacked, so an answer proves IPv6 egress
+		ln, err := net.Listen("tcp6", *ipv6Listen)
+		if err != nil {
+			log.Error("judge listen failed", "err", err, "addr", *ipv6Listen)
+			os.Exit(1)
+		}
+		log.Info("judge listening", "addr", ln.Addr().String(), "tls", false, "ipv6_only", true)
+		go func() { errCh <- judge.NewServer().Serve(judge.NewListener(ln, nil)) }()
+	}
+
+	if *dnsListen != "" {
+		if *dnsZone == "" {
+			fmt.Fprintln(os.Stderr, "judge: --dns-listen needs --dns-zone")
+			os.Exit(1)
+		}
+		dns := &judge.DNSServer{Zone: *dnsZone}
+		if *dnsAnswer != "" {
+			if dns.Answer = net.ParseIP(*dnsAnswer); dns.Answer == nil {
+				fmt.Fprintln(os.Stderr, "judge: invalid --dns-answer")
+				os.Exit(1)
+			}
+		}
+		pc, err := net.ListenPacket("udp", *dnsListen)
+		if err != nil {
+			log.Error("judge dns listen failed", "err", err, "addr", *dnsListen)
+			os.Exit(1)
+		}
+		log.Info("judge dns listening", "addr", pc.LocalAddr().String(), "zone", *dnsZone)
+		go func() { errCh <- dns.Serve(pc) }()
+	}
+
+	err := <-errCh
+	log.Error("judge stopped", "err", err)
+	os.Exit(1)
+}
+
+// loadJudgeCert loads certFile/keyFile, or generates a self-signed
+// certificate for hosts and optionally writes it to certOut.
+func loadJudgeCert(certFile, keyFile, hosts, certOut string) (tls.Certificate, error) {
+	if certFile != "" || keyFile != "" {
+		return tls.LoadX509KeyPair(certFile, keyFile)
+	}
+
+	cert, certPEM, err := judge.SelfSignedCert(strings.Split(hosts, ","))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if certOut != "" {
+		if err := os.WriteFile(certOut, certPEM, 0644); err != nil {
+			return tls.Certificate{}, err
+		}
+	}
+	return cert, nil
+}
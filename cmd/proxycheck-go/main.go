@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net"
 	"os"
 	"time"
 
@@ -16,17 +17,50 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "judge" {
+		runJudge(os.Args[2:])
+		return
+	}
+
 	var cfg model.Config
 
-	flag.StringVar(&cfg.ProxyType, "type", "socks5", "proxy type: https | socks5")
+	flag.StringVar(&cfg.ProxyType, "type", "socks5", "proxy type for lines without a scheme: socks5 | socks5h | socks4 | socks4a | http | https | auto")
 	flag.IntVar(&cfg.TimeoutSeconds, "timeout", 5, "timeout in seconds for each proxy check")
 	flag.StringVar(&cfg.InputFile, "input", "", "path to file with proxy list")
 	flag.StringVar(&cfg.OutputFile, "output", "", "optional path to write results (json/csv)")
 	flag.StringVar(&cfg.OutputFormat, "format", "json", "output format: json | csv")
-	flag.BoolVar(&cfg.CheckCapabilities, "check-capabilities", false, "probe smtp/pop3/imap/udp capabilities")
+	flag.BoolVar(&cfg.CheckCapabilities, "check-capabilities", false, "probe smtp/pop3/imap/udp and the capability matrix for alive proxies")
+	capabilitiesFile := flag.String("capabilities-file", "", "JSON file with extra named capability probes (host:port targets, tls, expect regex)")
+	leakRulesFile := flag.String("leak-rules", "", "JSON file of anonymity leak rules (header, value pattern, severity) replacing the built-in set")
+	targetsFile := flag.String("targets", "", "JSON file of sites (url, expected status, body match) to fetch through every alive proxy")
+	flag.BoolVar(&cfg.IPv6Check, "ipv6", false, "probe whether alive proxies can egress over IPv6")
+	flag.StringVar(&cfg.IPv6URL, "ipv6-url", "", "IPv6-only endpoint answering with the client address, e.g. the /ip of \"proxycheck-go judge --ipv6-listen\" (default: api6.ipify.org with the default judge, skipped with --judge-url)")
+	flag.BoolVar(&cfg.Fingerprint, "fingerprint", false, "identify the proxy software (Squid, Tinyproxy, 3proxy, MikroTik, ...) from headers, error pages and handshakes")
+	flag.StringVar(&cfg.DNSLeakZone, "dns-leak-zone", "", "zone served by the judge's DNS stand-in (\"proxycheck-go judge --dns-zone\"); resolve a unique name in it through every alive proxy and report the resolvers")
+	flag.BoolVar(&cfg.IntegrityCheck, "integrity", false, "fetch a known payload over http and https through alive proxies and flag tampering; needs a \"proxycheck-go judge\" instance as --judge-url, or --integrity-url")
+	flag.StringVar(&cfg.IntegrityURL, "integrity-url", "", "payload URL for the integrity check, fetched with both schemes (default: /payload on a \"proxycheck-go judge\" --judge-url host); implies --integrity")
+	flag.StringVar(&cfg.IntegritySHA256, "integrity-sha256", "", "expected hex SHA-256 of the --integrity-url body")
+	flag.IntVar(&cfg.Samples, "samples", 0, "collect N successful latency samples per alive proxy (min/median/p95/max, jitter)")
+	flag.BoolVar(&cfg.SampleReuse, "sample-reuse", false, "reuse one keep-alive connection for all samples instead of re-dialing")
+	flag.IntVar(&cfg.BandwidthBytes, "bandwidth-bytes", 0, "measure download/upload throughput with a payload of N bytes per direction (0 = off)")
+	flag.StringVar(&cfg.BandwidthDownloadURL, "bandwidth-download-url", "", "download URL for the bandwidth test (default: /bytes/N on the judge host)")
+	flag.StringVar(&cfg.BandwidthUploadURL, "bandwidth-upload-url", "", "URL that accepts POST uploads for the bandwidth test (default: /post on the judge host)")
+	flag.IntVar(&cfg.RotationRequests, "rotation", 0, "profile exit-IP rotation with N >= 2 judge requests per proxy (0 = off)")
+	flag.DurationVar(&cfg.RotationInterval, "rotation-interval", 0, "pause between rotation requests, e.g. 10s (spans the sticky-session test)")
+	flag.StringVar(&cfg.SessionFormat, "session-format", "{user}-session-{session}", "username template for sticky sessions; {user} and {session} are substituted")
 	flag.IntVar(&cfg.Concurrency, "concurrency", 50, "number of concurrent workers")
 	flag.BoolVar(&cfg.Verbose, "verbose", false, "enable debug logs")
 	flag.IntVar(&cfg.Retries, "retries", 3, "number of retry attempts per proxy (min 1)")
+	flag.StringVar(&cfg.UDPResolver, "udp-resolver", "8.8.8.8:53", "DNS server (host:port) queried through the SOCKS5 UDP relay")
+	flag.BoolVar(&cfg.ProxyTLS, "proxy-tls", false, "connect to every proxy endpoint over TLS (also per line via scheme+tls://; https proxies always are)")
+	flag.StringVar(&cfg.ProxyTLSServerName, "proxy-sni", "", "override SNI / certificate name for TLS proxy endpoints")
+	proxyCA := flag.String("proxy-ca", "", "PEM CA bundle to verify TLS proxy endpoints")
+	proxyInsecure := flag.Bool("proxy-insecure", false, "skip certificate verification for TLS proxy endpoints")
+	flag.StringVar(&cfg.JudgeURL, "judge-url", "https://httpbin.org/get", "httpbin-compatible judge endpoint (e.g. a \"proxycheck-go judge\" instance)")
+	flag.StringVar(&cfg.JudgePlainURL, "judge-http-url", "", "http:// judge endpoint for GET forwarding checks (default: --judge-url with http scheme)")
+	judgeCA := flag.String("judge-ca", "", "extra PEM CA/certificate to trust for the judge")
+	flag.StringVar(&cfg.RealClientIP, "real-ip", "", "our own public IP to look for in leaked headers (default: asked from the judge without a proxy)")
+	tlsPins := flag.String("tls-pin", "", "comma-separated sha256/<base64> public key pins (leaf or issuer) expected in the judge's HTTPS chain")
 
 	flag.Parse()
 
@@ -37,18 +71,96 @@ func main() {
 		os.Exit(1)
 	}
 
+	if cfg.Samples < 0 {
+		fmt.Fprintln(os.Stderr, "--samples must be 0 (off) or more")
+		os.Exit(1)
+	}
+
+	if cfg.RotationRequests != 0 && cfg.RotationRequests < 2 {
+		fmt.Fprintln(os.Stderr, "--rotation must be 0 (off) or at least 2: one request shows no rotation")
+		os.Exit(1)
+	}
+
 	if cfg.Retries < 1 {
 		cfg.Retries = 1
 	}
 
+	if cfg.IntegrityURL != "" {
+		if cfg.IntegritySHA256 == "" {
+			fmt.Fprintln(os.Stderr, "--integrity-url needs --integrity-sha256")
+			os.Exit(1)
+		}
+		cfg.IntegrityCheck = true
+	}
+	if cfg.IntegrityCheck && !checker.IntegrityAvailable(cfg) {
+		log.Warn("--integrity needs a \"proxycheck-go judge\" instance as --judge-url, or --integrity-url; skipping the integrity check")
+		cfg.IntegrityCheck = false
+	}
+
 	log.Info("starting proxycheck-go",
 		"type", cfg.ProxyType,
 		"timeout_seconds", cfg.TimeoutSeconds,
 		"concurrency", cfg.Concurrency,
 		"check_capabilities", cfg.CheckCapabilities,
 		"retries", cfg.Retries,
+		"samples", cfg.Samples,
+		"bandwidth_bytes", cfg.BandwidthBytes,
+		"rotation", cfg.RotationRequests,
+		"integrity", cfg.IntegrityCheck,
+		"dns_leak_zone", cfg.DNSLeakZone,
+		"judge_url", cfg.JudgeURL,
 	)
 
+	proxyTLSConfig, err := checker.NewProxyTLSConfig(*proxyCA, *proxyInsecure)
+	if err != nil {
+		log.Error("failed to load proxy tls settings", "err", err)
+		os.Exit(1)
+	}
+	cfg.ProxyTLSConfig = proxyTLSConfig
+
+	judgeTLSConfig, err := checker.NewJudgeTLSConfig(*judgeCA)
+	if err != nil {
+		log.Error("failed to load judge ca", "err", err)
+		os.Exit(1)
+	}
+	cfg.JudgeTLSConfig = judgeTLSConfig
+
+	cfg.JudgeTLSPins, err = checker.ParseTLSPins(*tlsPins)
+	if err != nil {
+		log.Error("invalid --tls-pin", "err", err)
+		os.Exit(1)
+	}
+
+	if *capabilitiesFile != "" {
+		probes, err := checker.LoadCapabilityProbes(*capabilitiesFile)
+		if err != nil {
+			log.Error("failed to load capability probes", "err", err)
+			os.Exit(1)
+		}
+		cfg.CapabilityProbes = probes
+		log.Info("capability probes loaded", "count", len(probes))
+	}
+
+	if *leakRulesFile != "" {
+		rules, err := checker.LoadLeakRules(*leakRulesFile)
+		if err != nil {
+			log.Error("failed to load leak rules", "err", err)
+			os.Exit(1)
+		}
+		cfg.LeakRules = rules
+		log.Info("leak rules loaded", "count", len(rules))
+	}
+
+	if *targetsFile != "" {
+		targets, err := checker.LoadTargets(*targetsFile)
+		if err != nil {
+			log.Error("failed to load targets", "err", err)
+			os.Exit(1)
+		}
+		cfg.Targets = targets
+		log.Info("targets loaded", "count", len(targets))
+	}
+
 	proxies, err := parser.LoadFromFile(cfg.InputFile)
 	if err != nil {
 		log.Error("failed to load proxies", "err", err)
@@ -66,6 +178,20 @@ func main() {
 	cfg.Resolver = resolver
 
 	ctx := context.Background()
+
+	if cfg.RealClientIP == "" {
+		realIP, err := checker.DiscoverRealIP(ctx, cfg)
+		if err != nil {
+			log.Warn("could not discover our public ip, transparent proxies are only detected by their headers", "err", err)
+		} else {
+			cfg.RealClientIP = realIP
+			log.Info("real client ip discovered", "ip", realIP)
+		}
+	} else if net.ParseIP(cfg.RealClientIP) == nil {
+		log.Error("invalid --real-ip", "ip", cfg.RealClientIP)
+		os.Exit(1)
+	}
+
 	start := time.Now()
 
 	results := checker.RunBatch(ctx, proxies, cfg)
@@ -2,10 +2,9 @@ module github.com/August26/proxycheck-go
 
 go 1.25
 
-require golang.org/x/net v0.46.0
+require github.com/oschwald/geoip2-golang v1.13.0
 
 require (
-	github.com/oschwald/geoip2-golang v1.13.0 // indirect
 	github.com/oschwald/maxminddb-golang v1.13.0 // indirect
 	golang.org/x/sys v0.37.0 // indirect
 )
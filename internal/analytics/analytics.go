@@ -1,6 +1,7 @@
 package analytics
 
 import (
+	"net/http"
 	"strconv"
 	"time"
 
@@ -16,6 +17,16 @@ func Compute(results []model.ProxyCheckResult, totalDuration time.Duration) mode
         latencyCount   int64
         fraudSum    int64
         fraudCount  int64
+        downSum, upSum, ttfbSum       float64
+        downCount, upCount, ttfbCount int
+        errorClasses = map[model.ErrorClass]int{}
+        tampered     = 0
+        intercepted  = 0
+        dnsLeaks     = 0
+        software     = map[string]int{}
+        leakHeaders  = map[string]int{}
+        targets      []model.TargetStats
+        targetIndex  = map[string]int{}
     )
 
     for _, r := range results {
@@ -30,10 +41,72 @@ func Compute(results []model.ProxyCheckResult, totalDuration time.Duration) mode
             latencyCount++
         }
 
+        if r.Tampered {
+            tampered++
+        }
+        if r.TLSIntercepted {
+            intercepted++
+        }
+        if r.DNSLeak != nil && r.DNSLeak.Leak {
+            dnsLeaks++
+        }
+        if r.Software != "" {
+            software[r.Software]++
+        }
+        // count each proxy once per header, whatever its casing
+        seenLeaks := map[string]bool{}
+        for _, leak := range r.AnonymityLeaks {
+            name := http.CanonicalHeaderKey(leak.Header)
+            if !seenLeaks[name] {
+                seenLeaks[name] = true
+                leakHeaders[name]++
+            }
+        }
+
         if r.FraudScore > 0 {
             fraudSum += int64(r.FraudScore)
             fraudCount++
         }
+
+        if r.ErrorClass != "" {
+            errorClasses[r.ErrorClass]++
+        }
+        // enrichment failures don't fail the check, but are worth counting
+        if r.Stages.Geo.Status == model.StageFailed {
+            errorClasses[model.ErrorClassGeoLookupFailed]++
+        }
+
+        if r.DownloadKBps > 0 {
+            downSum += r.DownloadKBps
+            downCount++
+        }
+        if r.UploadKBps > 0 {
+            upSum += r.UploadKBps
+            upCount++
+        }
+        if r.TTFBMs > 0 {
+            ttfbSum += float64(r.TTFBMs)
+            ttfbCount++
+        }
+
+        for _, t := range r.Targets {
+            i, ok := targetIndex[t.Name]
+            if !ok {
+                i = len(targets)
+                targetIndex[t.Name] = i
+                targets = append(targets, model.TargetStats{Name: t.Name})
+            }
+            targets[i].Tested++
+            if t.Reachable {
+                targets[i].Reachable++
+            }
+            if t.Blocked {
+                targets[i].Blocked++
+            }
+        }
+    }
+    for i := range targets {
+        targets[i].ReachablePct = float64(targets[i].Reachable) / float64(targets[i].Tested) * 100.0
     }
 
     avgLatency := 0.0
@@ -52,6 +125,16 @@ func Compute(results []model.ProxyCheckResult, totalDuration time.Duration) mode
     }
 
     return model.BatchStats{
+        AvgDownloadKBps:       average(downSum, downCount),
+        AvgUploadKBps:         average(upSum, upCount),
+        AvgTTFBMs:             average(ttfbSum, ttfbCount),
+        ErrorClasses:          errorClasses,
+        Targets:               targets,
+        TamperedProxies:       tampered,
+        TLSInterceptedProxies: intercepted,
+        DNSLeakProxies:        dnsLeaks,
+        Software:              software,
+        LeakHeaders:           leakHeaders,
         TotalProxies:          total,
         UniqueProxies:         len(uniqueSet),
         AliveProxies:          alive,
@@ -61,3 +144,10 @@ func Compute(results []model.ProxyCheckResult, totalDuration time.Duration) mode
         TotalProcessingTimeMs: totalDuration.Milliseconds(),
     }
 }
+
+func average(sum float64, count int) float64 {
+    if count == 0 {
+        return 0
+    }
+    return sum / float64(count)
+}
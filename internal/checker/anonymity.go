@@ -1,6 +1,12 @@
 package checker
 
-// import "strings"
+import (
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
 
 // ClassifyAnonymity tries to guess anonymity level based on what the
 // destination server reports seeing.
@@ -23,41 +29,108 @@ type AnonymityInput struct {
 	// HeadersObserved: headers that the remote service saw that may leak
 	// info. For example "X-Forwarded-For: <real client ip>" or "Via: proxy".
 	HeadersObserved map[string]string
+
+	// HeaderOrder: header names in the order and casing the remote
+	// service received them. Empty if it doesn't report them.
+	HeaderOrder []string
+
+	// RealClientIP: our own public IP, as the judge sees us without a
+	// proxy. Empty if it could not be determined.
+	RealClientIP string
+
+	// Rules: what counts as a leak; nil means defaultLeakRules.
+	Rules []model.LeakRule
 }
 
 // DetermineAnonymity returns "transparent", "anonymous", "elite", or "unknown".
 func DetermineAnonymity(in AnonymityInput) string {
+	level, _ := EvaluateAnonymity(in)
+	return level
+}
+
+// EvaluateAnonymity grades the proxy like DetermineAnonymity and returns
+// the leak rules that matched along with it, so a grade below "elite"
+// comes with the headers that caused it.
+func EvaluateAnonymity(in AnonymityInput) (string, []model.AnonymityLeak) {
+	leaks := matchLeakRules(in)
 	if in.ProxyExitIP == "" || in.IPReportedByServer == "" {
-		return "unknown"
-	}
-
-	// If the server sees our real client IP (not equal to proxy exit IP),
-	// then the proxy is *transparent* (it leaked us).
-	if in.IPReportedByServer != in.ProxyExitIP {
-		return "transparent"
-	}
-
-	leakHeaders := []string{
-        "X-Forwarded-For",
-        "X-Forwarded-Host",
-        "Forwarded",
-        "Via",
-        "X-Real-IP",
-        "Proxy-Connection",
-    }
-
-	leaked := false
-    for _, h := range leakHeaders {
-        if v := in.HeadersObserved[h]; v != "" {
-            leaked = true
-            break
-        }
-    }
-
-    if leaked {
-        return "anonymous"
-    }
-
-	// If we got here: remote only sees proxy IP, and we didn't obviously announce we're a proxy.
-	return "elite"
+		return "unknown", leaks
+	}
+
+	// If the server sees our real client IP anywhere, or more than one
+	// IP in its origin, then the proxy is *transparent* (it leaked us).
+	if len(RealIPLeaks(in)) > 0 || in.IPReportedByServer != in.ProxyExitIP {
+		return "transparent", leaks
+	}
+
+	// If we got here: remote only sees proxy IP, and the rules decide
+	// whether the proxy announced itself.
+	level := "elite"
+	for _, leak := range leaks {
+		switch leak.Severity {
+		case severityHigh:
+			return "transparent", leaks
+		case severityMedium:
+			level = "anonymous"
+		}
+	}
+	return level, leaks
+}
+
+// RealIPLeaks returns where in.RealClientIP shows up in what the server
+// saw: "origin" and/or the names of the headers carrying it, sorted.
+// Values are split into address tokens, so X-Forwarded-For chains,
+// Forwarded "for=" parameters and host:port forms all match.
+func RealIPLeaks(in AnonymityInput) []string {
+	clientIP := net.ParseIP(in.RealClientIP)
+	if clientIP == nil {
+		return nil
+	}
+
+	var leaks []string
+	if containsIP(in.ProxyExitIP, clientIP) || containsIP(in.IPReportedByServer, clientIP) {
+		leaks = append(leaks, "origin")
+	}
+	var headers []string
+	for name, value := range in.HeadersObserved {
+		// Host names the judge, not the client
+		if strings.EqualFold(name, "Host") {
+			continue
+		}
+		if containsIP(value, clientIP) {
+			headers = append(headers, name)
+		}
+	}
+	sort.Strings(headers)
+	return append(leaks, headers...)
+}
+
+// containsIP reports whether any address token in value is ip, e.g. in
+// "203.0.113.7, 10.0.0.1", "for=\"[2001:db8::7]:4711\";proto=https" or
+// "1.1 203.0.113.7:3128 (squid)".
+func containsIP(value string, ip net.IP) bool {
+	for _, got := range addressTokens(value) {
+		if got.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// addressTokens returns the IP addresses in a header value, see containsIP.
+func addressTokens(value string) []net.IP {
+	tokens := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';' || r == '=' || r == '"' || r == ' ' || r == '\t' || r == '(' || r == ')'
+	})
+	var ips []net.IP
+	for _, tok := range tokens {
+		if host, _, err := net.SplitHostPort(tok); err == nil {
+			tok = host
+		}
+		tok = strings.TrimSuffix(strings.TrimPrefix(tok, "["), "]")
+		if ip := net.ParseIP(tok); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
 }
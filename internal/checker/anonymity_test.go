@@ -0,0 +1,43 @@
+package checker
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDetermineAnonymityRealIP(t *testing.T) {
+	const exit, ours = "198.51.100.20", "203.0.113.7"
+
+	cases := []struct {
+		name      string
+		origin    string
+		headers   map[string]string
+		realIP    string
+		want      string
+		wantLeaks []string
+	}{
+		{"elite", exit, map[string]string{"Host": "judge"}, ours, "elite", nil},
+		{"judge on our host", exit, map[string]string{"Host": ours + ":8080"}, ours, "elite", nil},
+		{"xff chain", exit, map[string]string{"X-Forwarded-For": "10.0.0.5, " + ours}, ours, "transparent", []string{"X-Forwarded-For"}},
+		{"forwarded for", exit, map[string]string{"Forwarded": `for="` + ours + `:51234";proto=http;by=` + exit}, ours, "transparent", []string{"Forwarded"}},
+		{"ipv6 forwarded", exit, map[string]string{"Forwarded": `for="[2001:db8::7]:4711"`}, "2001:db8::7", "transparent", []string{"Forwarded"}},
+		{"custom header", exit, map[string]string{"X-Client-Ip": ours, "Via": "1.1 proxy"}, ours, "transparent", []string{"X-Client-Ip"}},
+		{"exits with our ip", ours, nil, ours, "transparent", []string{"origin"}},
+		{"prefix is not a match", exit, map[string]string{"X-Forwarded-For": "203.0.113.70"}, ours, "anonymous", nil},
+		{"no real ip known", exit, map[string]string{"X-Forwarded-For": ours}, "", "anonymous", nil},
+	}
+	for _, c := range cases {
+		in := AnonymityInput{
+			IPReportedByServer: c.origin,
+			ProxyExitIP:        c.origin,
+			HeadersObserved:    c.headers,
+			RealClientIP:       c.realIP,
+		}
+		if got := DetermineAnonymity(in); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+		if got := RealIPLeaks(in); !slices.Equal(got, c.wantLeaks) {
+			t.Errorf("%s: leaks %q, want %q", c.name, got, c.wantLeaks)
+		}
+	}
+}
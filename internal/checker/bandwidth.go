@@ -0,0 +1,172 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+// bandwidthResult is what one bandwidth test measured; zero values mean
+// the direction failed.
+type bandwidthResult struct {
+	DownloadKBps float64
+	UploadKBps   float64
+	TTFBMs       int64
+}
+
+// measureBandwidth downloads and uploads cfg.BandwidthBytes through p,
+// each direction on a fresh connection with its own --timeout budget.
+// A transfer cut short by the deadline still yields a rate from the
+// bytes that made it, which is exactly the throttled-proxy case.
+func measureBandwidth(ctx context.Context, p model.ProxyInput, cfg model.Config) bandwidthResult {
+	var res bandwidthResult
+	downURL, upURL := bandwidthURLs(cfg)
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+
+	client := buildClientForProxy(p, newProxyDialer(p, cfg), cfg)
+	downCtx, cancel := context.WithTimeout(ctx, timeout)
+	res.DownloadKBps, res.TTFBMs, _ = downloadRate(downCtx, client, downURL)
+	cancel()
+	client.CloseIdleConnections()
+
+	client = buildClientForProxy(p, newProxyDialer(p, cfg), cfg)
+	upCtx, cancel := context.WithTimeout(ctx, timeout)
+	res.UploadKBps, _ = uploadRate(upCtx, client, upURL, int64(cfg.BandwidthBytes))
+	cancel()
+	client.CloseIdleConnections()
+
+	return res
+}
+
+// bandwidthURLs returns the download and upload endpoints: the configured
+// ones, or /bytes/N and /post on the judge host.
+func bandwidthURLs(cfg model.Config) (downURL, upURL string) {
+	downURL, upURL = cfg.BandwidthDownloadURL, cfg.BandwidthUploadURL
+	judgeURL, _ := judgeURLs(cfg)
+	u, err := url.Parse(judgeURL)
+	if err != nil {
+		return downURL, upURL
+	}
+	if downURL == "" {
+		u.Path = "/bytes/" + strconv.Itoa(cfg.BandwidthBytes)
+		downURL = u.String()
+	}
+	if upURL == "" {
+		u.Path = "/post"
+		upURL = u.String()
+	}
+	return downURL, upURL
+}
+
+// downloadRate fetches target and returns the body rate in KB/s, measured
+// from the first response byte, and the time to that first byte.
+func downloadRate(ctx context.Context, client *http.Client, target string) (float64, int64, error) {
+	var firstByte time.Time
+	trace := &httptrace.ClientTrace{
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, target, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return 0, 0, fmt.Errorf("download: status %d", resp.StatusCode)
+	}
+
+	n, err := io.Copy(io.Discard, resp.Body)
+	if err != nil && ctx.Err() == nil {
+		return 0, 0, err
+	}
+	ttfb := firstByte.Sub(start).Milliseconds()
+	return kbps(n, time.Since(firstByte)), ttfb, nil
+}
+
+// uploadRate POSTs size bytes to target and returns the rate in KB/s,
+// measured from the moment the tunnel is up until the server answers
+// (it only answers once it has read the whole body).
+func uploadRate(ctx context.Context, client *http.Client, target string, size int64) (float64, error) {
+	var gotConn time.Time
+	trace := &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) { gotConn = time.Now() },
+	}
+
+	body := &countingReader{r: io.LimitReader(&payloadReader{}, size)}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodPost, target, body)
+	if err != nil {
+		return 0, err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil || gotConn.IsZero() {
+			return 0, err
+		}
+		// deadline hit mid-upload: rate from what was sent so far
+		return kbps(body.n.Load(), time.Since(gotConn)), nil
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return 0, fmt.Errorf("upload: status %d", resp.StatusCode)
+	}
+	return kbps(size, time.Since(gotConn)), nil
+}
+
+func kbps(n int64, d time.Duration) float64 {
+	if n <= 0 || d <= 0 {
+		return 0
+	}
+	return float64(n) / 1024 / d.Seconds()
+}
+
+// countingReader counts bytes handed to the transport, which reads the
+// body from its own goroutine.
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// payloadReader is an endless stream of pseudo-random upload bytes
+// (xorshift), so a compressing proxy can't shrink it. The generator
+// carries on across reads: restarting it would repeat every chunk.
+type payloadReader struct {
+	state uint32 // xorshift state, seeded on first use
+}
+
+func (r *payloadReader) Read(p []byte) (int, error) {
+	if r.state == 0 {
+		r.state = 2463534242
+	}
+	x := r.state
+	for i := range p {
+		x ^= x << 13
+		x ^= x >> 17
+		x ^= x << 5
+		p[i] = byte(x)
+	}
+	r.state = x
+	return len(p), nil
+}
@@ -0,0 +1,20 @@
+package checker
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPayloadReaderDoesNotRepeat(t *testing.T) {
+	r := &payloadReader{}
+	a := make([]byte, 32<<10)
+	b := make([]byte, 32<<10)
+	r.Read(a)
+	r.Read(b)
+	if bytes.Equal(a, b) {
+		t.Fatal("consecutive reads returned the same bytes")
+	}
+	if bytes.Equal(a[:len(a)/2], a[len(a)/2:]) {
+		t.Fatal("a single read repeats itself")
+	}
+}
@@ -0,0 +1,156 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+// capabilityReadTimeout caps how long a matrix probe waits for a banner,
+// so one silent port doesn't eat the whole per-proxy timeout.
+const capabilityReadTimeout = 5 * time.Second
+
+// maxCapabilityBanner bounds how much of a banner is read for matching.
+const maxCapabilityBanner = 4096
+
+// LoadCapabilityProbes reads a capability matrix from a JSON file:
+//
+//	{"probes": [
+//	  {"name": "ssh", "targets": ["github.com:22"], "expect": "^SSH-"},
+//	  {"name": "rdp", "targets": ["rdp.example.com:3389"]}
+//	]}
+//
+// Names must be unique; every target must be host:port and every expect
+// pattern a valid regular expression.
+func LoadCapabilityProbes(path string) ([]model.CapabilityProbe, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read capabilities file: %w", err)
+	}
+
+	var file struct {
+		Probes []model.CapabilityProbe `json:"probes"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse capabilities file: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for i := range file.Probes {
+		probe := &file.Probes[i]
+		if probe.Name == "" {
+			return nil, fmt.Errorf("capability probe #%d has no name", i+1)
+		}
+		if seen[probe.Name] {
+			return nil, fmt.Errorf("duplicate capability probe %q", probe.Name)
+		}
+		seen[probe.Name] = true
+
+		if len(probe.Targets) == 0 {
+			return nil, fmt.Errorf("capability probe %q has no targets", probe.Name)
+		}
+		for _, t := range probe.Targets {
+			if _, _, err := net.SplitHostPort(t); err != nil {
+				return nil, fmt.Errorf("capability probe %q: bad target %q: %w", probe.Name, t, err)
+			}
+		}
+
+		if probe.Expect != "" {
+			re, err := regexp.Compile(probe.Expect)
+			if err != nil {
+				return nil, fmt.Errorf("capability probe %q: bad expect pattern: %w", probe.Name, err)
+			}
+			probe.ExpectRe = re
+		}
+	}
+	return file.Probes, nil
+}
+
+// runCapabilityMatrix runs every probe through d in parallel and returns
+// probe name -> passed. It returns nil when no probes are configured.
+func runCapabilityMatrix(ctx context.Context, d contextDialer, probes []model.CapabilityProbe) map[string]bool {
+	if len(probes) == 0 {
+		return nil
+	}
+
+	var (
+		mu  sync.Mutex
+		out = make(map[string]bool, len(probes))
+		wg  sync.WaitGroup
+	)
+	for _, probe := range probes {
+		probe := probe
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ok := false
+			for _, target := range probe.Targets {
+				if err := probeCapability(ctx, d, target, probe); err == nil {
+					ok = true
+					break
+				}
+			}
+
+			mu.Lock()
+			out[probe.Name] = ok
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+// probeCapability opens a tunnel to target and, for TLS probes, completes
+// the handshake. With an expect pattern it then reads what the server
+// sends first until the pattern matches; without one the open tunnel
+// (and handshake) is the whole test.
+func probeCapability(ctx context.Context, d contextDialer, target string, probe model.CapabilityProbe) error {
+	conn, err := d.DialContext(ctx, "tcp", target)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stop := watchConnContext(ctx, conn)
+	defer stop()
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > capabilityReadTimeout {
+		conn.SetDeadline(time.Now().Add(capabilityReadTimeout))
+	}
+
+	if probe.TLS {
+		host, _, _ := net.SplitHostPort(target)
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+		if err := tlsConn.Handshake(); err != nil {
+			return fmt.Errorf("tls handshake: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	if probe.ExpectRe == nil {
+		return nil
+	}
+
+	banner := make([]byte, 0, 512)
+	buf := make([]byte, 512)
+	for len(banner) < maxCapabilityBanner {
+		n, err := conn.Read(buf)
+		banner = append(banner, buf[:n]...)
+		if probe.ExpectRe.Match(banner) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("banner %q does not match %q: %w", trimBanner(string(banner)), probe.Expect, err)
+		}
+	}
+	return errors.New("banner does not match " + probe.Expect)
+}
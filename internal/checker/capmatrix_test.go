@@ -0,0 +1,64 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+// directDialer connects without a proxy, standing in for a tunnel.
+type directDialer struct{}
+
+func (directDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, network, addr)
+}
+
+// startBannerServer accepts connections and writes banner to each.
+func startBannerServer(t *testing.T, banner string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte(banner))
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestRunCapabilityMatrix(t *testing.T) {
+	ssh := startBannerServer(t, "SSH-2.0-OpenSSH_9.6\r\n")
+	irc := startBannerServer(t, "NOTICE AUTH :*** Looking up your hostname\r\n")
+
+	probes := []model.CapabilityProbe{
+		// first target is closed, second answers
+		{Name: "ssh", Targets: []string{"127.0.0.1:1", ssh}, ExpectRe: regexp.MustCompile(`^SSH-`)},
+		{Name: "ssh_wrong_banner", Targets: []string{irc}, ExpectRe: regexp.MustCompile(`^SSH-`)},
+		{Name: "connect_only", Targets: []string{irc}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	got := runCapabilityMatrix(ctx, directDialer{}, probes)
+
+	want := map[string]bool{"ssh": true, "ssh_wrong_banner": false, "connect_only": true}
+	for name, ok := range want {
+		if got[name] != ok {
+			t.Errorf("%s: got %v, want %v", name, got[name], ok)
+		}
+	}
+}
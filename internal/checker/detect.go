@@ -0,0 +1,193 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+// protocolPreference orders detected protocols from most to least useful.
+// "https" is an endpoint that only answered over TLS.
+var protocolPreference = []string{"socks5", "http", "https", "socks4a", "socks4"}
+
+// detectProtocols works out what an untyped endpoint speaks. Every probe
+// opens its own connection, sends the protocol's opening message and
+// classifies the endpoint by the reply bytes. The probes run in parallel
+// and the result is ordered by protocolPreference (best first).
+func detectProtocols(ctx context.Context, p model.ProxyInput, cfg model.Config) []string {
+	judgeURL, plainJudgeURL := judgeURLs(cfg)
+	judge, err := url.Parse(judgeURL)
+	if err != nil {
+		return nil
+	}
+
+	probes := []struct {
+		tls   bool // connect over TLS, as to an "https" proxy
+		probe func(net.Conn) []string
+	}{
+		{false, func(c net.Conn) []string { return detectSOCKS5(c) }},
+		{false, func(c net.Conn) []string { return detectSOCKS4(c, p.Username, judge.Hostname()) }},
+		{false, func(c net.Conn) []string { return detectHTTPConnect(c, p, judge.Hostname(), "http") }},
+		{false, func(c net.Conn) []string { return detectHTTPGet(c, p, plainJudgeURL) }},
+		{true, func(c net.Conn) []string { return detectHTTPConnect(c, p, judge.Hostname(), "https") }},
+	}
+
+	var (
+		mu    sync.Mutex
+		found = map[string]bool{}
+		wg    sync.WaitGroup
+	)
+	for _, probe := range probes {
+		probe := probe
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			dialCtx, pp := ctx, p
+			if probe.tls {
+				// endpoints that don't speak TLS often never answer a
+				// ClientHello: don't let the handshake wait for ctx
+				var cancel context.CancelFunc
+				dialCtx, cancel = context.WithTimeout(ctx, detectReadTimeout)
+				defer cancel()
+				pp.Type = "https"
+			}
+			conn, err := newProxyDialer(pp, cfg).DialContext(dialCtx, "tcp", "")
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			stop := watchConnContext(ctx, conn)
+			defer stop()
+			if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > detectReadTimeout {
+				conn.SetReadDeadline(time.Now().Add(detectReadTimeout))
+			}
+
+			for _, proto := range probe.probe(conn) {
+				mu.Lock()
+				found[proto] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	var out []string
+	for _, proto := range protocolPreference {
+		if found[proto] {
+			out = append(out, proto)
+		}
+	}
+	return out
+}
+
+// detectSOCKS5 offers "no auth" and "username/password". Any SOCKS5 server
+// answers VER=0x05 and its chosen METHOD (0xFF if neither is acceptable).
+func detectSOCKS5(conn net.Conn) []string {
+	if _, err := conn.Write([]byte{0x05, 0x02, 0x00, 0x02}); err != nil {
+		return nil
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil
+	}
+	if reply[0] != 0x05 {
+		return nil
+	}
+	return []string{"socks5"}
+}
+
+// detectSOCKS4 sends a SOCKS4a CONNECT for host:80. A granted request means
+// the proxy resolves hostnames (SOCKS4a); a well-formed rejection still
+// proves plain SOCKS4.
+func detectSOCKS4(conn net.Conn, userID, host string) []string {
+	req := []byte{0x04, 0x01, 0x00, 80, 0, 0, 0, 1}
+	req = append(req, userID...)
+	req = append(req, 0x00)
+	req = append(req, host...)
+	req = append(req, 0x00)
+	if _, err := conn.Write(req); err != nil {
+		return nil
+	}
+
+	reply := make([]byte, 8)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil
+	}
+	if reply[0] != 0x00 && reply[0] != 0x04 {
+		return nil
+	}
+	switch reply[1] {
+	case socks4Granted:
+		return []string{"socks4a", "socks4"}
+	case socks4Rejected, socks4IdentdDown, socks4IdentdMismatch:
+		return []string{"socks4"}
+	default:
+		return nil
+	}
+}
+
+// detectHTTPConnect asks for a tunnel to host:443 and reports proto if
+// the endpoint grants it. 2xx means CONNECT works; 407 still identifies
+// an HTTP proxy that tunnels once authenticated.
+func detectHTTPConnect(conn net.Conn, p model.ProxyInput, host, proto string) []string {
+	target := net.JoinHostPort(host, "443")
+	req := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n%s\r\n", target, target, proxyAuthHeader(p))
+	if !httpProxyAnswers(conn, req) {
+		return nil
+	}
+	return []string{proto}
+}
+
+// detectHTTPGet sends an absolute-form GET, which only forward proxies
+// (and the odd lenient web server) answer with success.
+func detectHTTPGet(conn net.Conn, p model.ProxyInput, target string) []string {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil
+	}
+	req := fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nConnection: close\r\n%s\r\n", target, u.Host, proxyAuthHeader(p))
+	if !httpProxyAnswers(conn, req) {
+		return nil
+	}
+	return []string{"http"}
+}
+
+// httpProxyAnswers writes req and reports whether the endpoint replied
+// with an HTTP status line that is a success or 407 Proxy Auth Required.
+func httpProxyAnswers(conn net.Conn, req string) bool {
+	if _, err := conn.Write([]byte(req)); err != nil {
+		return false
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode/100 == 2 || resp.StatusCode == http.StatusProxyAuthRequired
+}
+
+// proxyAuthHeader returns a "Proxy-Authorization" header line (with CRLF)
+// for p's credentials, or "" if p has none.
+func proxyAuthHeader(p model.ProxyInput) string {
+	if p.Username == "" && p.Password == "" {
+		return ""
+	}
+	token := base64.StdEncoding.EncodeToString([]byte(p.Username + ":" + p.Password))
+	return "Proxy-Authorization: Basic " + token + "\r\n"
+}
+
+// detectReadTimeout caps how long a probe waits for a reply. Endpoints
+// often sit silent on a protocol they don't speak, and we don't want one
+// probe to hold the whole detection until the per-proxy timeout.
+const detectReadTimeout = 3 * time.Second
@@ -0,0 +1,109 @@
+package checker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/August26/proxycheck-go/internal/judge"
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+// checkDNSLeak makes p resolve a name under cfg.DNSLeakZone that nobody
+// has looked up before, by opening a tunnel to it, and then asks the
+// judge which resolvers came asking for that name. The tunnel itself
+// only has to trigger the lookup; it fails whenever the stand-in has no
+// address to answer with.
+//
+// socks5 and socks4 resolve locally, so what shows up is our own
+// resolver: the leak a real client of such a proxy has. socks5h,
+// socks4a and HTTP proxies resolve on the proxy side.
+func checkDNSLeak(ctx context.Context, p model.ProxyInput, cfg model.Config, exitCountry string) *model.DNSLeakResult {
+	res := &model.DNSLeakResult{Name: dnsLeakName(cfg.DNSLeakZone)}
+
+	d := tunnelDialer(p, cfg)
+	if d == nil {
+		res.Error = "protocol can't open tunnels"
+		return res
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, time.Duration(cfg.TimeoutSeconds)*time.Second)
+	if conn, err := d.DialContext(dialCtx, "tcp", net.JoinHostPort(res.Name, "80")); err == nil {
+		conn.Close()
+	}
+	cancel()
+
+	queries, err := fetchDNSQueries(ctx, p, cfg, res.Name)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if len(queries) == 0 {
+		res.Error = "no resolver asked for the name"
+		return res
+	}
+
+	seen := map[string]bool{}
+	for _, q := range queries {
+		if seen[q.Resolver] {
+			continue
+		}
+		seen[q.Resolver] = true
+
+		r := model.DNSResolver{IP: q.Resolver}
+		if info, err := cfg.Resolver.Lookup(q.Resolver); err == nil {
+			r.Country, r.ASN, r.ISP = info.Country, info.ASN, info.ISP
+		}
+		if exitCountry != "" && r.Country != "" && r.Country != exitCountry {
+			res.Leak = true
+		}
+		res.Resolvers = append(res.Resolvers, r)
+	}
+	return res
+}
+
+// dnsLeakName returns a random, never before seen name under zone.
+func dnsLeakName(zone string) string {
+	var b [8]byte
+	rand.Read(b[:])
+	return "pc-" + hex.EncodeToString(b[:]) + "." + strings.TrimSuffix(zone, ".")
+}
+
+// fetchDNSQueries asks the judge's /dns/<name> through p which resolvers
+// looked up name.
+func fetchDNSQueries(ctx context.Context, p model.ProxyInput, cfg model.Config, name string) ([]judge.DNSQuery, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, time.Duration(cfg.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	client := buildClientForProxy(p, newProxyDialer(p, cfg), cfg)
+	defer client.CloseIdleConnections()
+
+	judgeURL, _ := judgeURLs(cfg)
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, withPath(judgeURL, "/dns/"+name), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errors.New("judge has no /dns endpoint")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("judge /dns: unexpected status %d", resp.StatusCode)
+	}
+	var parsed judge.DNSResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("%w: %v", errJudgeParse, err)
+	}
+	return parsed.Queries, nil
+}
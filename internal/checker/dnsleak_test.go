@@ -0,0 +1,154 @@
+package checker
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/August26/proxycheck-go/internal/judge"
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+type staticResolver map[string]model.GeoInfo
+
+func (r staticResolver) Lookup(ip string) (model.GeoInfo, error) {
+	info, ok := r[ip]
+	if !ok {
+		return model.GeoInfo{}, errors.New("not found")
+	}
+	return info, nil
+}
+
+// startResolvingSOCKS5 runs a no-auth SOCKS5 proxy that resolves domain
+// targets with the DNS server at dnsAddr, as a socks5h proxy would with
+// its own resolver.
+func startResolvingSOCKS5(t *testing.T, dnsAddr string) model.ProxyInput {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "udp", dnsAddr)
+		},
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				head := make([]byte, 2)
+				if _, err := io.ReadFull(conn, head); err != nil {
+					return
+				}
+				io.CopyN(io.Discard, conn, int64(head[1]))
+				conn.Write([]byte{0x05, 0x00})
+
+				req := make([]byte, 4)
+				if _, err := io.ReadFull(conn, req); err != nil {
+					return
+				}
+				var host string
+				switch req[3] {
+				case 0x01:
+					ip := make([]byte, 4)
+					io.ReadFull(conn, ip)
+					host = net.IP(ip).String()
+				case 0x03:
+					n := make([]byte, 1)
+					io.ReadFull(conn, n)
+					name := make([]byte, n[0])
+					io.ReadFull(conn, name)
+					ips, err := resolver.LookupHost(context.Background(), string(name))
+					if err != nil {
+						conn.Write([]byte{0x05, 0x04, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+						return
+					}
+					host = ips[0]
+				default:
+					return
+				}
+				port := make([]byte, 2)
+				io.ReadFull(conn, port)
+
+				up, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))))
+				if err != nil {
+					conn.Write([]byte{0x05, 0x05, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				defer up.Close()
+				conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+				go io.Copy(up, conn)
+				io.Copy(conn, up)
+			}()
+		}
+	}()
+
+	return proxyInputFor(t, ln.Addr())
+}
+
+func TestCheckDNSLeak(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+	defer pc.Close()
+	go (&judge.DNSServer{Zone: "leak.test"}).Serve(pc)
+
+	srv := httptest.NewServer(judge.NewHandler())
+	defer srv.Close()
+
+	cfg := model.Config{
+		TimeoutSeconds: 5,
+		JudgeURL:       srv.URL + "/get",
+		DNSLeakZone:    "leak.test",
+		Resolver:       staticResolver{"127.0.0.1": {Country: "DE", ASN: "AS64500"}},
+	}
+
+	cases := []struct {
+		typ      string
+		exit     string
+		wantLeak bool
+		wantErr  bool
+	}{
+		{"socks5h", "DE", false, false},
+		{"socks5h", "US", true, false},
+		// socks5 resolves here, with the system resolver, which never
+		// reaches the stand-in
+		{"socks5", "US", false, true},
+	}
+	for _, c := range cases {
+		p := startResolvingSOCKS5(t, pc.LocalAddr().String())
+		p.Type = c.typ
+
+		res := checkDNSLeak(context.Background(), p, cfg, c.exit)
+		if c.wantErr {
+			if res.Error == "" {
+				t.Errorf("%s: expected an error, got %+v", c.typ, res)
+			}
+			continue
+		}
+		if res.Error != "" {
+			t.Fatalf("%s: unexpected error: %s", c.typ, res.Error)
+		}
+		if len(res.Resolvers) != 1 || res.Resolvers[0].IP != "127.0.0.1" || res.Resolvers[0].ASN != "AS64500" {
+			t.Errorf("%s: resolvers %+v", c.typ, res.Resolvers)
+		}
+		if res.Leak != c.wantLeak {
+			t.Errorf("%s/%s: leak %v, want %v", c.typ, c.exit, res.Leak, c.wantLeak)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+var (
+	// errJudgeParse wraps a judge body that isn't the JSON we expect.
+	errJudgeParse = errors.New("judge: unparsable response")
+	// errGeoLookup wraps a GeoIP lookup failure of the exit IP.
+	errGeoLookup = errors.New("geo lookup failed")
+)
+
+// httpStatusError is an unexpected HTTP status, either from the proxy
+// itself (CONNECT reply, 407 on a forwarded GET) or from the judge.
+type httpStatusError struct {
+	Code    int
+	Status  string
+	Connect bool // the status was the proxy's reply to CONNECT
+}
+
+func (e *httpStatusError) Error() string {
+	if e.Connect {
+		return fmt.Sprintf("proxy refused CONNECT: %s", e.Status)
+	}
+	return fmt.Sprintf("unexpected HTTP status: %s", e.Status)
+}
+
+// checkConnectResponse is the Transport.OnProxyConnectResponse hook of
+// HTTP proxy clients: it marks the tunnel phase and turns a non-200
+// reply into an *httpStatusError instead of the transport's plain text.
+func checkConnectResponse(ctx context.Context, resp *http.Response) error {
+	markPhase(ctx, phaseTunnelUp)
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	return &httpStatusError{Code: resp.StatusCode, Status: resp.Status, Connect: true}
+}
+
+// classifyError maps a check error onto the taxonomy in model.ErrorClass.
+// p tells a missing login (auth_required) from a wrong one (auth_failed)
+// when the proxy answers 407.
+func classifyError(err error, p model.ProxyInput) model.ErrorClass {
+	if err == nil {
+		return ""
+	}
+	hasAuth := p.Username != "" || p.Password != ""
+
+	var (
+		dnsErr    *net.DNSError
+		statusErr *httpStatusError
+		repErr    *socks5ReplyError
+		netErr    net.Error
+	)
+	switch {
+	case errors.Is(err, errGeoLookup):
+		return model.ErrorClassGeoLookupFailed
+	case errors.Is(err, errJudgeParse):
+		return model.ErrorClassJudgeParse
+	case errors.Is(err, errSOCKS5AuthRequired):
+		return model.ErrorClassAuthRequired
+	case errors.Is(err, errSOCKS5AuthFailed), errors.Is(err, errSOCKS4IdentdMismatch):
+		return model.ErrorClassAuthFailed
+	case errors.Is(err, errSOCKS4Rejected), errors.Is(err, errSOCKS4IdentdDown):
+		return model.ErrorClassSOCKSRuleDenied
+	case errors.As(err, &repErr):
+		switch repErr.Code {
+		case 0x02:
+			return model.ErrorClassSOCKSRuleDenied
+		case 0x06:
+			return model.ErrorClassConnectTimeout
+		default:
+			return model.ErrorClassConnectRefused
+		}
+	case errors.As(err, &statusErr):
+		if statusErr.Code == http.StatusProxyAuthRequired {
+			if hasAuth {
+				return model.ErrorClassAuthFailed
+			}
+			return model.ErrorClassAuthRequired
+		}
+		return model.ErrorClassUpstreamHTTP
+	case isTLSError(err):
+		return model.ErrorClassTLS
+	case errors.As(err, &dnsErr):
+		return model.ErrorClassDNSFailure
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET):
+		return model.ErrorClassConnectRefused
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return model.ErrorClassConnectTimeout
+	default:
+		return model.ErrorClassOther
+	}
+}
+
+func isTLSError(err error) bool {
+	var (
+		verifyErr   *tls.CertificateVerificationError
+		recordErr   tls.RecordHeaderError
+		alertErr    tls.AlertError
+		unknownCA   x509.UnknownAuthorityError
+		hostnameErr x509.HostnameError
+		invalidCert x509.CertificateInvalidError
+		opErr       *net.OpError
+	)
+	// a TLS alert from the peer surfaces as an OpError with Op "remote error"
+	if errors.As(err, &opErr) && opErr.Op == "remote error" {
+		return true
+	}
+	return errors.As(err, &verifyErr) ||
+		errors.As(err, &recordErr) ||
+		errors.As(err, &alertErr) ||
+		errors.As(err, &unknownCA) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidCert)
+}
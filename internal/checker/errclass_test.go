@@ -0,0 +1,43 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+func TestClassifyError(t *testing.T) {
+	noAuth := model.ProxyInput{Host: "192.0.2.1", Port: 8080}
+	withAuth := model.ProxyInput{Host: "192.0.2.1", Port: 8080, Username: "u", Password: "p"}
+
+	var d net.Dialer
+	_, refused := d.Dial("tcp", "127.0.0.1:1")
+
+	cases := []struct {
+		name string
+		err  error
+		p    model.ProxyInput
+		want model.ErrorClass
+	}{
+		{"dns", &net.DNSError{Err: "no such host", Name: "proxy.invalid", IsNotFound: true}, noAuth, model.ErrorClassDNSFailure},
+		{"refused", refused, noAuth, model.ErrorClassConnectRefused},
+		{"timeout", fmt.Errorf("get: %w", context.DeadlineExceeded), noAuth, model.ErrorClassConnectTimeout},
+		{"socks5 no method", errSOCKS5AuthRequired, noAuth, model.ErrorClassAuthRequired},
+		{"socks5 bad login", errSOCKS5AuthFailed, withAuth, model.ErrorClassAuthFailed},
+		{"socks5 ruleset", &socks5ReplyError{Code: 0x02}, noAuth, model.ErrorClassSOCKSRuleDenied},
+		{"socks4 rejected", errSOCKS4Rejected, noAuth, model.ErrorClassSOCKSRuleDenied},
+		{"407 without login", &httpStatusError{Code: 407, Connect: true}, noAuth, model.ErrorClassAuthRequired},
+		{"407 with login", &httpStatusError{Code: 407}, withAuth, model.ErrorClassAuthFailed},
+		{"502 from proxy", &httpStatusError{Code: 502, Connect: true}, noAuth, model.ErrorClassUpstreamHTTP},
+		{"judge parse", fmt.Errorf("%w: eof", errJudgeParse), noAuth, model.ErrorClassJudgeParse},
+		{"geo", fmt.Errorf("%w: not found", errGeoLookup), noAuth, model.ErrorClassGeoLookupFailed},
+	}
+	for _, c := range cases {
+		if got := classifyError(c.err, c.p); got != c.want {
+			t.Errorf("%s: got %q, want %q (err: %v)", c.name, got, c.want, c.err)
+		}
+	}
+}
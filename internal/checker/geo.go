@@ -81,12 +81,15 @@ func (r *Resolver) Lookup(ipStr string) (GeoInfo, error) {
 		cityName = cityRec.Subdivisions[0].Names["en"]
 	}
 
-	isp := ""
+	isp, asn := "", ""
 	if asnRec, err := r.asnDB.ASN(ip); err == nil {
 		isp = asnRec.AutonomousSystemOrganization
+		if asnRec.AutonomousSystemNumber != 0 {
+			asn = fmt.Sprintf("AS%d", asnRec.AutonomousSystemNumber)
+		}
 	}
 
-	return GeoInfo{Country: country, City: cityName, ISP: isp}, nil
+	return GeoInfo{Country: country, City: cityName, ISP: isp, ASN: asn}, nil
 }
 
 // --- helpers ---
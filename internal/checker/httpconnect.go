@@ -0,0 +1,87 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+// connectDialer opens raw TCP tunnels through an HTTP proxy with CONNECT,
+// so non-HTTP probes (mail, capability matrix) work over HTTP(S) proxies.
+type connectDialer struct {
+	proxy *proxyDialer
+	auth  string // Proxy-Authorization header line, "" without credentials
+}
+
+// newConnectDialer returns a CONNECT dialer for p that reaches the proxy through pd.
+func newConnectDialer(p model.ProxyInput, pd *proxyDialer) *connectDialer {
+	return &connectDialer{
+		proxy: pd,
+		auth:  proxyAuthHeader(p),
+	}
+}
+
+// DialContext asks the proxy for a tunnel to addr and returns the tunnel
+// once the proxy answered 2xx.
+func (d *connectDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("http connect: unsupported network %q", network)
+	}
+
+	conn, err := d.proxy.DialContext(ctx, "tcp", "")
+	if err != nil {
+		return nil, err
+	}
+
+	stop := watchConnContext(ctx, conn)
+	br, err := connectHandshake(conn, addr, d.auth)
+	if stopErr := stop(); err == nil {
+		err = stopErr
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	markPhase(ctx, phaseTunnelUp)
+
+	// Servers that talk first (SMTP, SSH) may have sent their banner in
+	// the same packet as the CONNECT reply; don't lose what br buffered.
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+func connectHandshake(conn net.Conn, addr, auth string) (*bufio.Reader, error) {
+	req := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n%s\r\n", addr, addr, auth)
+	if _, err := conn.Write([]byte(req)); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		return nil, fmt.Errorf("http connect: read reply: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		resp.Body.Close()
+		return nil, &httpStatusError{Code: resp.StatusCode, Status: resp.Status, Connect: true}
+	}
+	return br, nil
+}
+
+// bufferedConn is a net.Conn whose reads drain a bufio.Reader first.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
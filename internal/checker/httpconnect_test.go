@@ -0,0 +1,62 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+// startFakeConnectProxy answers one CONNECT with status and, in the same
+// write, the first bytes of the tunnelled server.
+func startFakeConnectProxy(t *testing.T, reply string) model.ProxyInput {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, err := http.ReadRequest(bufio.NewReader(conn)); err != nil {
+			return
+		}
+		conn.Write([]byte(reply))
+		conn.Read(make([]byte, 1))
+	}()
+
+	return proxyInputFor(t, ln.Addr())
+}
+
+func TestConnectDialer_KeepsBannerSentWithReply(t *testing.T) {
+	p := startFakeConnectProxy(t, "HTTP/1.1 200 Connection established\r\n\r\nSSH-2.0-test\r\n")
+	d := newConnectDialer(p, newProxyDialer(p, model.Config{}))
+
+	conn, err := d.DialContext(context.Background(), "tcp", "192.0.2.1:22")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	defer conn.Close()
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || line != "SSH-2.0-test\r\n" {
+		t.Fatalf("banner lost: %q, %v", line, err)
+	}
+}
+
+func TestConnectDialer_Refused(t *testing.T) {
+	p := startFakeConnectProxy(t, "HTTP/1.1 403 Forbidden\r\nContent-Length: 0\r\n\r\n")
+	d := newConnectDialer(p, newProxyDialer(p, model.Config{}))
+
+	if _, err := d.DialContext(context.Background(), "tcp", "192.0.2.1:25"); err == nil {
+		t.Fatalf("expected error for 403 reply")
+	}
+}
@@ -0,0 +1,275 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/August26/proxycheck-go/internal/judge"
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+// maxPayloadBody bounds how much of the integrity payload is read.
+const maxPayloadBody = 1 << 20
+
+// securityHeaders are compared between the original payload and what
+// came through the proxy, in this order.
+var securityHeaders = []string{
+	"Content-Security-Policy",
+	"Strict-Transport-Security",
+	"X-Frame-Options",
+	"X-Content-Type-Options",
+	"Referrer-Policy",
+	"Permissions-Policy",
+}
+
+// IntegrityInput is one fetch of the integrity payload and what the
+// server is known to have sent.
+type IntegrityInput struct {
+	Body    []byte
+	Headers http.Header
+
+	// ExpectedSHA256 is the hex SHA-256 of the original body.
+	ExpectedSHA256 string
+
+	// Reference is the original body, when known, to describe what
+	// changed instead of just reporting a hash mismatch.
+	Reference []byte
+
+	// ExpectedHeaders maps the security headers the server sends to
+	// their values; headers missing here are expected to be absent.
+	// nil means they are not known and are not compared.
+	ExpectedHeaders map[string]string
+
+	// PlainHTTP skips HTTPS-only headers (HSTS), which servers are free
+	// to leave out of cleartext answers.
+	PlainHTTP bool
+}
+
+// DetectTampering returns how the fetched payload differs from the
+// original, body first, then headers. An empty result means it arrived
+// intact.
+func DetectTampering(in IntegrityInput) []string {
+	var diff []string
+
+	sum := sha256.Sum256(in.Body)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, in.ExpectedSHA256) {
+		if in.Reference != nil {
+			diff = append(diff, bodyDiff(in.Reference, in.Body)...)
+		} else {
+			diff = append(diff, fmt.Sprintf("body sha256 %.12s, want %.12s (%d bytes)", got, strings.ToLower(in.ExpectedSHA256), len(in.Body)))
+		}
+	}
+
+	for _, name := range securityHeaders {
+		if in.ExpectedHeaders == nil || in.PlainHTTP && name == "Strict-Transport-Security" {
+			continue
+		}
+		want := in.ExpectedHeaders[name]
+		got := in.Headers.Get(name)
+		switch {
+		case want == got:
+		case got == "":
+			diff = append(diff, "header "+name+" stripped")
+		case want == "":
+			diff = append(diff, fmt.Sprintf("header %s added: %q", name, got))
+		default:
+			diff = append(diff, fmt.Sprintf("header %s changed: %q, want %q", name, got, want))
+		}
+	}
+	return diff
+}
+
+// linkRe finds the URLs a page loads or sends the user to.
+var linkRe = regexp.MustCompile(`(?i)\b(?:href|src|action)\s*=\s*["']([^"']+)["']`)
+
+// bodyDiff describes how got differs from ref: the changed span, what
+// was inserted, injected tags and links that were added or rewritten.
+func bodyDiff(ref, got []byte) []string {
+	prefix := 0
+	for prefix < len(ref) && prefix < len(got) && ref[prefix] == got[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(ref)-prefix && suffix < len(got)-prefix &&
+		ref[len(ref)-1-suffix] == got[len(got)-1-suffix] {
+		suffix++
+	}
+	// a pure insertion can often sit a few bytes earlier just as well
+	// ("<" + "script ...><" vs "<script ...>" + "<"); slide it back to
+	// start on a tag so the excerpt reads like what was injected
+	if len(ref) < len(got) && prefix+suffix == len(ref) {
+		for prefix > 0 && got[prefix] != '<' && got[len(got)-suffix-1] == ref[prefix-1] {
+			prefix--
+			suffix++
+		}
+	}
+	removed := ref[prefix : len(ref)-suffix]
+	added := got[prefix : len(got)-suffix]
+
+	diff := []string{fmt.Sprintf("body changed at byte %d: %d bytes removed, %d added", prefix, len(removed), len(added))}
+	if len(added) > 0 {
+		diff = append(diff, fmt.Sprintf("inserted %q", excerpt(added, 80)))
+	}
+
+	for _, tag := range []string{"<script", "<iframe"} {
+		if countFold(got, tag) > countFold(ref, tag) {
+			diff = append(diff, "injected "+tag+">")
+		}
+	}
+
+	refLinks := map[string]bool{}
+	for _, m := range linkRe.FindAllSubmatch(ref, -1) {
+		refLinks[string(m[1])] = true
+	}
+	for _, m := range linkRe.FindAllSubmatch(got, -1) {
+		if link := string(m[1]); !refLinks[link] {
+			diff = append(diff, "new link "+link)
+			refLinks[link] = true // report each once
+		}
+	}
+	return diff
+}
+
+func countFold(b []byte, s string) int {
+	return bytes.Count(bytes.ToLower(b), []byte(s))
+}
+
+func excerpt(b []byte, n int) string {
+	if len(b) <= n {
+		return string(b)
+	}
+	return string(b[:n]) + "..."
+}
+
+// checkIntegrity fetches the integrity payload through p over HTTPS and
+// then plain HTTP, each with its own --timeout budget, and compares both
+// to the original. Without a configured URL it is the judge's /payload,
+// which is known byte for byte. For a configured URL only the hash is
+// known; an HTTPS copy that matches it serves as the original body and
+// headers for the plain HTTP one.
+func checkIntegrity(ctx context.Context, p model.ProxyInput, cfg model.Config) *model.IntegrityResult {
+	plainURL, tlsURL := integrityURLs(cfg)
+
+	client := buildClientForProxy(p, newProxyDialer(p, cfg), cfg)
+	defer client.CloseIdleConnections()
+	// a redirect (e.g. http -> https) means the payload was not served
+	// over the scheme under test
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	in := IntegrityInput{ExpectedSHA256: cfg.IntegritySHA256}
+	if cfg.IntegrityURL == "" {
+		in.ExpectedSHA256 = judge.PayloadSHA256
+		in.Reference = judge.Payload
+		in.ExpectedHeaders = judge.PayloadHeaders
+	}
+
+	res := &model.IntegrityResult{}
+	var tlsBody []byte
+	var tlsHeaders http.Header
+	res.HTTPS, tlsBody, tlsHeaders = fetchIntegrity(ctx, client, tlsURL, in, cfg)
+
+	if cfg.IntegrityURL != "" && res.HTTPS.Error == "" && len(res.HTTPS.Diff) == 0 {
+		in.Reference = tlsBody
+		in.ExpectedHeaders = map[string]string{}
+		for _, name := range securityHeaders {
+			if v := tlsHeaders.Get(name); v != "" {
+				in.ExpectedHeaders[name] = v
+			}
+		}
+	}
+	in.PlainHTTP = true
+	res.HTTP, _, _ = fetchIntegrity(ctx, client, plainURL, in, cfg)
+	return res
+}
+
+// fetchIntegrity fetches target and compares it using in (Body and
+// Headers are filled in here). It also returns the body and headers.
+func fetchIntegrity(ctx context.Context, client *http.Client, target string, in IntegrityInput, cfg model.Config) (model.IntegrityFetch, []byte, http.Header) {
+	out := model.IntegrityFetch{URL: target}
+
+	reqCtx, cancel := context.WithTimeout(ctx, time.Duration(cfg.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, target, nil)
+	if err != nil {
+		out.Error = err.Error()
+		return out, nil, nil
+	}
+	// injectors usually only touch what looks like a browser loading a page
+	req.Header.Set("User-Agent", browserUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		out.Error = err.Error()
+		return out, nil, nil
+	}
+	defer resp.Body.Close()
+
+	out.StatusCode = resp.StatusCode
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPayloadBody))
+	if err != nil {
+		out.Error = err.Error()
+		return out, nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		out.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+		return out, nil, nil
+	}
+
+	sum := sha256.Sum256(body)
+	out.SHA256 = hex.EncodeToString(sum[:])
+	in.Body, in.Headers = body, resp.Header
+	out.Diff = DetectTampering(in)
+	return out, body, resp.Header
+}
+
+// IntegrityAvailable reports whether there is a payload to check
+// integrity with: --integrity-url, or a self-hosted judge. httpbin.org
+// has no /payload.
+func IntegrityAvailable(cfg model.Config) bool {
+	plainURL, _ := integrityURLs(cfg)
+	return plainURL != ""
+}
+
+// integrityURLs returns the payload URL over http and https: /payload
+// on the two judge endpoints, or --integrity-url with each scheme. Both
+// are "" with the default judge.
+func integrityURLs(cfg model.Config) (plainURL, tlsURL string) {
+	if cfg.IntegrityURL == "" {
+		judgeURL, plainJudgeURL := judgeURLs(cfg)
+		if judgeURL == defaultJudgeURL {
+			return "", ""
+		}
+		return withPath(plainJudgeURL, "/payload"), withPath(judgeURL, "/payload")
+	}
+	u, err := url.Parse(cfg.IntegrityURL)
+	if err != nil {
+		return cfg.IntegrityURL, cfg.IntegrityURL
+	}
+	u.Scheme = "http"
+	plainURL = u.String()
+	u.Scheme = "https"
+	tlsURL = u.String()
+	return plainURL, tlsURL
+}
+
+func withPath(rawURL, path string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.Path, u.RawQuery = path, ""
+	return u.String()
+}
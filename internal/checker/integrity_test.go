@@ -0,0 +1,86 @@
+package checker
+
+import (
+	"bytes"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/August26/proxycheck-go/internal/judge"
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+func TestDetectTampering(t *testing.T) {
+	headers := func(drop ...string) http.Header {
+		h := http.Header{}
+		for k, v := range judge.PayloadHeaders {
+			h.Set(k, v)
+		}
+		for _, k := range drop {
+			h.Del(k)
+		}
+		return h
+	}
+	injected := bytes.Replace(judge.Payload, []byte("</body>"), []byte(`<script src="http://ads.example.net/x.js"></script></body>`), 1)
+
+	cases := []struct {
+		name string
+		in   IntegrityInput
+		want []string // lines the diff must contain; nil means intact
+	}{
+		{"intact", IntegrityInput{Body: judge.Payload, Headers: headers()}, nil},
+		{"hsts stripped on http", IntegrityInput{Body: judge.Payload, Headers: headers("Strict-Transport-Security"), PlainHTTP: true}, nil},
+		{"csp stripped", IntegrityInput{Body: judge.Payload, Headers: headers("Content-Security-Policy")}, []string{
+			"header Content-Security-Policy stripped",
+		}},
+		{"script injected", IntegrityInput{Body: injected, Headers: headers()}, []string{
+			`inserted "<script src=\"http://ads.example.net/x.js\"></script>"`,
+			"injected <script>",
+			"new link http://ads.example.net/x.js",
+		}},
+	}
+	for _, c := range cases {
+		c.in.ExpectedSHA256 = judge.PayloadSHA256
+		c.in.Reference = judge.Payload
+		c.in.ExpectedHeaders = judge.PayloadHeaders
+		got := DetectTampering(c.in)
+		if (len(got) == 0) != (c.want == nil) {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+			continue
+		}
+		for _, line := range c.want {
+			if !slices.Contains(got, line) {
+				t.Errorf("%s: %q missing from %q", c.name, line, got)
+			}
+		}
+	}
+
+	// without the original body only the hash can be compared
+	got := DetectTampering(IntegrityInput{Body: []byte("hello"), ExpectedSHA256: judge.PayloadSHA256})
+	if len(got) != 1 {
+		t.Errorf("hash only: got %q, want one difference", got)
+	}
+}
+
+func TestIntegrityURLs(t *testing.T) {
+	cases := []struct {
+		cfg                model.Config
+		wantPlain, wantTLS string
+	}{
+		// httpbin.org has no /payload
+		{model.Config{}, "", ""},
+		{model.Config{JudgeURL: defaultJudgeURL}, "", ""},
+		{model.Config{JudgeURL: "https://judge.example.com:8443/get", JudgePlainURL: "http://judge.example.com:8080/get"},
+			"http://judge.example.com:8080/payload", "https://judge.example.com:8443/payload"},
+		{model.Config{IntegrityURL: "https://cdn.example.com/p.html"}, "http://cdn.example.com/p.html", "https://cdn.example.com/p.html"},
+	}
+	for _, c := range cases {
+		plain, tls := integrityURLs(c.cfg)
+		if plain != c.wantPlain || tls != c.wantTLS {
+			t.Errorf("%+v: got %q %q, want %q %q", c.cfg, plain, tls, c.wantPlain, c.wantTLS)
+		}
+		if IntegrityAvailable(c.cfg) != (c.wantPlain != "") {
+			t.Errorf("%+v: IntegrityAvailable is %v", c.cfg, c.wantPlain == "")
+		}
+	}
+}
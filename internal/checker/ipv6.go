@@ -0,0 +1,88 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+// defaultIPv6URL only has AAAA records, so a response proves the proxy
+// itself can egress over IPv6 (we never connect to it directly). It is
+// only used together with the default judge: whoever runs their own
+// judge keeps traffic in-house and sets --ipv6-url, e.g. to the /ip
+// endpoint of "proxycheck-go judge --ipv6-listen".
+const defaultIPv6URL = "https://api6.ipify.org"
+
+// ipv6URL returns the IPv6 probe endpoint, "" when there is none.
+func ipv6URL(cfg model.Config) string {
+	if cfg.IPv6URL != "" {
+		return cfg.IPv6URL
+	}
+	if judgeURL, _ := judgeURLs(cfg); judgeURL == defaultJudgeURL {
+		return defaultIPv6URL
+	}
+	return ""
+}
+
+// checkIPv6Exit probes p's IPv6 egress with a fresh client and its own
+// --timeout budget.
+func checkIPv6Exit(ctx context.Context, p model.ProxyInput, cfg model.Config) (ip string, ok bool) {
+	target := ipv6URL(cfg)
+	if target == "" {
+		return "", false
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	client := buildClientForProxy(p, newProxyDialer(p, cfg), cfg)
+	defer client.CloseIdleConnections()
+	return probeIPv6Exit(ctx, client, target)
+}
+
+// probeIPv6Exit requests target through client and returns the IPv6
+// address the endpoint saw. It answers with the bare address (ipify) or
+// {"origin": ...} (judge /ip). ok is false if the proxy could not reach
+// it or the answer is not an IPv6 address.
+func probeIPv6Exit(ctx context.Context, client *http.Client, target string) (ip string, ok bool) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return "", false
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if err != nil {
+		return "", false
+	}
+
+	ip = strings.TrimSpace(string(body))
+	if strings.HasPrefix(ip, "{") {
+		var parsed struct {
+			Origin string `json:"origin"`
+		}
+		if json.Unmarshal(body, &parsed) != nil {
+			return "", false
+		}
+		ip = parsed.Origin
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil || parsed.To4() != nil {
+		return "", false
+	}
+	return ip, true
+}
@@ -0,0 +1,51 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+func TestProbeIPv6Exit(t *testing.T) {
+	cases := []struct {
+		body   string
+		wantIP string
+		wantOK bool
+	}{
+		{"2001:db8::7\n", "2001:db8::7", true},
+		{`{"origin": "2001:db8::7"}`, "2001:db8::7", true},
+		{"203.0.113.7", "", false},
+		{`{"origin": "203.0.113.7"}`, "", false},
+		{`{"origin": `, "", false},
+	}
+	for _, c := range cases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(c.body))
+		}))
+		ip, ok := probeIPv6Exit(context.Background(), srv.Client(), srv.URL)
+		srv.Close()
+		if ip != c.wantIP || ok != c.wantOK {
+			t.Errorf("%q: got %q/%v, want %q/%v", c.body, ip, ok, c.wantIP, c.wantOK)
+		}
+	}
+}
+
+func TestIPv6URL(t *testing.T) {
+	cases := []struct {
+		cfg  model.Config
+		want string
+	}{
+		{model.Config{}, defaultIPv6URL},
+		{model.Config{JudgeURL: defaultJudgeURL}, defaultIPv6URL},
+		{model.Config{JudgeURL: "https://judge.example.com/get"}, ""},
+		{model.Config{JudgeURL: "https://judge.example.com/get", IPv6URL: "http://[2001:db8::1]:8080/ip"}, "http://[2001:db8::1]:8080/ip"},
+	}
+	for _, c := range cases {
+		if got := ipv6URL(c.cfg); got != c.want {
+			t.Errorf("%+v: got %q, want %q", c.cfg, got, c.want)
+		}
+	}
+}
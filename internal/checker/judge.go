@@ -0,0 +1,144 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+// Default judge endpoints, used when --judge-url / --judge-http-url are empty.
+const (
+	defaultJudgeURL      = "https://httpbin.org/get"
+	defaultPlainJudgeURL = "http://httpbin.org/get"
+)
+
+// judgeURLs returns the judge endpoint fetched through CONNECT (or a SOCKS
+// tunnel) and the http:// one fetched with an absolute-form GET by HTTP
+// proxies. Without an explicit plain URL it is derived from the first by
+// switching the scheme to http.
+func judgeURLs(cfg model.Config) (judgeURL, plainJudgeURL string) {
+	judgeURL = cfg.JudgeURL
+	if judgeURL == "" {
+		judgeURL = defaultJudgeURL
+	}
+
+	plainJudgeURL = cfg.JudgePlainURL
+	if plainJudgeURL == "" {
+		if cfg.JudgeURL == "" {
+			return judgeURL, defaultPlainJudgeURL
+		}
+		if u, err := url.Parse(judgeURL); err == nil {
+			u.Scheme = "http"
+			plainJudgeURL = u.String()
+		}
+	}
+	return judgeURL, plainJudgeURL
+}
+
+// judgeResponse matches the fields we care about from an httpbin-compatible
+// /get endpoint. header_order is only sent by "proxycheck-go judge".
+type judgeResponse struct {
+	Origin      string            `json:"origin"`  // what IP the judge thinks we are
+	Headers     map[string]string `json:"headers"` // headers seen by the judge
+	HeaderOrder []judgeHeader     `json:"header_order"`
+	Status      int               `json:"status"`
+
+	timings model.Timings  // client-side phases of this request
+	tls     tlsObservation // certificate chain received, https:// judges only
+}
+
+// judgeHeader is one header in the order and casing the judge received it.
+type judgeHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// fetchJudge GETs target through client and parses the judge response.
+// The request is traced, so the result carries its phase timings. The
+// TLS chain received is returned even when the request failed.
+func fetchJudge(ctx context.Context, client *http.Client, target string) (judgeResponse, error) {
+	clock := &phaseClock{}
+	req, err := http.NewRequestWithContext(withPhaseClock(ctx, clock), http.MethodGet, target, nil)
+	if err != nil {
+		return judgeResponse{}, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return judgeResponse{tls: observeTLS(nil, err)}, err
+	}
+	defer resp.Body.Close()
+	obs := observeTLS(resp, nil)
+
+	// 407 on a forwarded GET comes from the proxy, anything else non-2xx
+	// from the judge or whatever answered in its place
+	if resp.StatusCode/100 != 2 {
+		return judgeResponse{tls: obs}, &httpStatusError{Code: resp.StatusCode, Status: resp.Status}
+	}
+
+	var parsed judgeResponse
+	dec := json.NewDecoder(resp.Body)
+	if err := dec.Decode(&parsed); err != nil {
+		return judgeResponse{tls: obs}, fmt.Errorf("%w: %v", errJudgeParse, err)
+	}
+	parsed.Status = resp.StatusCode
+	parsed.timings = clock.timings()
+	parsed.tls = obs
+
+	return parsed, nil
+}
+
+// DiscoverRealIP asks the judge for our own public IP with a direct,
+// non-proxied request, so anonymity checks can look for it in what the
+// judge sees through each proxy.
+func DiscoverRealIP(ctx context.Context, cfg model.Config) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	client := &http.Client{Transport: newTransport(cfg)}
+	defer client.CloseIdleConnections()
+
+	judgeURL, _ := judgeURLs(cfg)
+	hb, err := fetchJudge(ctx, client, judgeURL)
+	if err != nil {
+		return "", err
+	}
+	ip := firstIPToken(hb.Origin)
+	if net.ParseIP(ip) == nil {
+		return "", fmt.Errorf("judge origin %q is not an IP address", hb.Origin)
+	}
+	return ip, nil
+}
+
+// NewJudgeTLSConfig returns the TLS config for requests to the judge and
+// other targets. caFile (PEM) is trusted in addition to the system roots,
+// e.g. the certificate written by "proxycheck-go judge --cert-out".
+// It returns nil (Go defaults) when caFile is empty.
+func NewJudgeTLSConfig(caFile string) (*tls.Config, error) {
+	if caFile == "" {
+		return nil, nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("read judge ca: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	return &tls.Config{RootCAs: pool}, nil
+}
@@ -0,0 +1,185 @@
+package checker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+const (
+	severityLow    = "low"    // reported only
+	severityMedium = "medium" // announces a proxy: anonymous
+	severityHigh   = "high"   // identifies the client: transparent
+)
+
+// orderLeakHeader is the AnonymityLeak.Header of header order anomalies.
+const orderLeakHeader = "header order"
+
+// defaultLeakRules are used unless --leak-rules replaces them. Headers
+// that carry a client address only grade "medium" here: they announce a
+// proxy, and whether the address is ours is RealIPLeaks' business.
+var defaultLeakRules = []model.LeakRule{
+	{Header: "X-Forwarded-For", Severity: severityMedium, Reason: "client address chain"},
+	{Header: "X-Forwarded-Host", Severity: severityMedium, Reason: "original host of a forwarded request"},
+	{Header: "Forwarded", Severity: severityMedium, Reason: "RFC 7239 forwarding record"},
+	{Header: "Via", Severity: severityMedium, Reason: "names the proxy"},
+	{Header: "X-Real-IP", Severity: severityMedium, Reason: "client address"},
+	{Header: "X-Client-IP", Severity: severityMedium, Reason: "client address"},
+	{Header: "Client-IP", Severity: severityMedium, Reason: "client address"},
+	{Header: "X-Originating-IP", Severity: severityMedium, Reason: "client address"},
+	{Header: "True-Client-IP", SkipExitIP: true, Severity: severityMedium, Reason: "client address"},
+	{Header: "CF-Connecting-IP", SkipExitIP: true, Severity: severityMedium, Reason: "client address"},
+	{Header: "X-Proxy-ID", Severity: severityMedium, Reason: "proxy identifier"},
+	{Header: "Proxy-Connection", Severity: severityMedium, Reason: "hop-by-hop proxy header passed on"},
+	{Header: "Cache-Control", Severity: severityMedium, Reason: "injected by a caching proxy, the judge request has none"},
+	{Order: true, Severity: severityMedium, Reason: "request rebuilt by the proxy"},
+}
+
+// sentHeaders are the headers Go's transport writes for a judge request,
+// in the order and casing it writes them. Proxies that only relay the
+// request leave both alone.
+var sentHeaders = []string{"Host", "User-Agent", "Accept-Encoding"}
+
+// LoadLeakRules reads an anonymity rule set that replaces the built-in
+// one:
+//
+//	{"rules": [
+//	  {"header": "X-Forwarded-For", "severity": "medium"},
+//	  {"header": "Via", "pattern": "squid", "severity": "low", "reason": "our own cache"},
+//	  {"header": "X-Customer-ID", "severity": "high"},
+//	  {"order": true, "severity": "low"}
+//	]}
+//
+// A rule matches a header by name in any casing, or with "order" the
+// headers we sent arriving reordered or re-cased. pattern narrows a
+// header rule to values matching it, case-insensitively.
+func LoadLeakRules(path string) ([]model.LeakRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read leak rules file: %w", err)
+	}
+
+	var file struct {
+		Rules []model.LeakRule `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse leak rules file: %w", err)
+	}
+
+	for i := range file.Rules {
+		r := &file.Rules[i]
+		switch {
+		case r.Order && r.Header != "":
+			return nil, fmt.Errorf("leak rule #%d: header and order are exclusive", i+1)
+		case !r.Order && r.Header == "":
+			return nil, fmt.Errorf("leak rule #%d: needs a header or order", i+1)
+		case r.Order && (r.Pattern != "" || r.SkipExitIP):
+			return nil, fmt.Errorf("leak rule #%d: pattern and skip_exit_ip only apply to headers", i+1)
+		}
+		switch r.Severity {
+		case severityLow, severityMedium, severityHigh:
+		default:
+			return nil, fmt.Errorf("leak rule #%d: severity %q, want low, medium or high", i+1, r.Severity)
+		}
+		if r.Pattern != "" {
+			re, err := regexp.Compile("(?i)" + r.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("leak rule #%d: bad pattern: %w", i+1, err)
+			}
+			r.PatternRe = re
+		}
+	}
+	return file.Rules, nil
+}
+
+// matchLeakRules returns a leak for every rule that matches what the
+// judge saw, in rule order. Header names are compared case-insensitively:
+// judges differ in how they spell them.
+func matchLeakRules(in AnonymityInput) []model.AnonymityLeak {
+	rules := in.Rules
+	if rules == nil {
+		rules = defaultLeakRules
+	}
+
+	names := make([]string, 0, len(in.HeadersObserved))
+	for name := range in.HeadersObserved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var leaks []model.AnonymityLeak
+	for _, r := range rules {
+		if r.Order {
+			if anomaly := headerOrderAnomaly(in.HeaderOrder); anomaly != "" {
+				leaks = append(leaks, model.AnonymityLeak{Header: orderLeakHeader, Value: anomaly, Severity: r.Severity, Reason: r.Reason})
+			}
+			continue
+		}
+		for _, name := range names {
+			value := in.HeadersObserved[name]
+			if !strings.EqualFold(name, r.Header) || value == "" {
+				continue
+			}
+			if r.PatternRe != nil && !r.PatternRe.MatchString(value) {
+				continue
+			}
+			if r.SkipExitIP && onlyIP(value, in.IPReportedByServer) {
+				continue
+			}
+			leaks = append(leaks, model.AnonymityLeak{Header: name, Value: value, Severity: r.Severity, Reason: r.Reason})
+		}
+	}
+	return leaks
+}
+
+// headerOrderAnomaly describes how the headers we sent arrived: which
+// one was re-cased, or the order they came in. "" if they arrived as
+// sent, or the order is not known.
+func headerOrderAnomaly(order []string) string {
+	var got []string
+	for _, name := range order {
+		if slices.ContainsFunc(sentHeaders, func(sent string) bool { return strings.EqualFold(name, sent) }) {
+			got = append(got, name)
+		}
+	}
+
+	last := -1
+	reordered := false
+	for _, name := range got {
+		i := slices.Index(sentHeaders, name)
+		if i < 0 {
+			return name + " re-cased"
+		}
+		if i < last {
+			reordered = true
+		}
+		last = i
+	}
+	if reordered {
+		return "reordered: " + strings.Join(got, ", ")
+	}
+	return ""
+}
+
+// onlyIP reports whether the addresses in value are all ip, and there
+// is at least one.
+func onlyIP(value, ip string) bool {
+	want := net.ParseIP(ip)
+	tokens := addressTokens(value)
+	if want == nil || len(tokens) == 0 {
+		return false
+	}
+	for _, got := range tokens {
+		if !got.Equal(want) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+package checker
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+func TestEvaluateAnonymity(t *testing.T) {
+	const exit = "198.51.100.20"
+	sent := []string{"Host", "User-Agent", "Accept-Encoding"}
+
+	custom := []model.LeakRule{
+		{Header: "X-Customer-Id", Severity: severityHigh},
+		{Header: "Via", Pattern: "squid", Severity: severityLow},
+	}
+	custom[1].PatternRe = regexp.MustCompile("(?i)squid")
+
+	cases := []struct {
+		name    string
+		headers map[string]string
+		order   []string
+		rules   []model.LeakRule
+		want    string
+		leaks   []string // matched headers
+	}{
+		{"clean", map[string]string{"Host": "judge", "User-Agent": "Go-http-client/1.1"}, sent, nil, "elite", nil},
+		{"lowercase keys", map[string]string{"x-forwarded-for": "10.0.0.5"}, nil, nil, "anonymous", []string{"x-forwarded-for"}},
+		{"new headers", map[string]string{"X-Proxy-Id": "abc", "Client-Ip": "10.0.0.5"}, nil, nil, "anonymous", []string{"Client-Ip", "X-Proxy-Id"}},
+		{"cdn sends exit ip", map[string]string{"Cf-Connecting-Ip": exit, "True-Client-Ip": exit}, nil, nil, "elite", nil},
+		{"cdn header with other ip", map[string]string{"Cf-Connecting-Ip": "10.0.0.5"}, nil, nil, "anonymous", []string{"Cf-Connecting-Ip"}},
+		{"cache-control injected", map[string]string{"Cache-Control": "max-age=259200"}, nil, nil, "anonymous", []string{"Cache-Control"}},
+		{"reordered", nil, []string{"User-Agent", "Host", "Accept-Encoding"}, nil, "anonymous", []string{orderLeakHeader}},
+		{"re-cased", nil, []string{"host", "user-agent", "accept-encoding"}, nil, "anonymous", []string{orderLeakHeader}},
+		{"extra headers keep order", nil, []string{"Host", "Via", "User-Agent", "X-Forwarded-For", "Accept-Encoding"}, nil, "elite", nil},
+		{"custom high", map[string]string{"x-customer-id": "42"}, nil, custom, "transparent", []string{"x-customer-id"}},
+		{"custom low", map[string]string{"Via": "1.1 squid"}, nil, custom, "elite", []string{"Via"}},
+		{"pattern miss", map[string]string{"Via": "1.1 varnish"}, nil, custom, "elite", nil},
+	}
+	for _, c := range cases {
+		level, leaks := EvaluateAnonymity(AnonymityInput{
+			IPReportedByServer: exit,
+			ProxyExitIP:        exit,
+			HeadersObserved:    c.headers,
+			HeaderOrder:        c.order,
+			Rules:              c.rules,
+		})
+		if level != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, level, c.want)
+		}
+		var got []string
+		for _, leak := range leaks {
+			got = append(got, leak.Header)
+		}
+		if !slices.Equal(got, c.leaks) {
+			t.Errorf("%s: leaks %q, want %q", c.name, got, c.leaks)
+		}
+	}
+}
+
+func TestLoadLeakRules(t *testing.T) {
+	dir := t.TempDir()
+	write := func(body string) string {
+		path := filepath.Join(dir, "rules.json")
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	rules, err := LoadLeakRules(write(`{"rules": [
+		{"header": "Via", "pattern": "SQUID", "severity": "low"},
+		{"order": true, "severity": "medium"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].PatternRe == nil || !rules[0].PatternRe.MatchString("1.1 squid") {
+		t.Errorf("rules not loaded as expected: %+v", rules)
+	}
+
+	for _, bad := range []string{
+		`{"rules": [{"header": "Via", "severity": "severe"}]}`,
+		`{"rules": [{"severity": "low"}]}`,
+		`{"rules": [{"header": "Via", "order": true, "severity": "low"}]}`,
+		`{"rules": [{"order": true, "pattern": "x", "severity": "low"}]}`,
+		`{"rules": [{"header": "Via", "pattern": "(", "severity": "low"}]}`,
+	} {
+		if _, err := LoadLeakRules(write(bad)); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+}
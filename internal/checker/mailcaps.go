@@ -1,63 +1,190 @@
 package checker
 
 import (
+	"bufio"
 	"context"
+	"crypto/tls"
+	"fmt"
 	"net"
 	"strconv"
-	"time"
-
-	"golang.org/x/net/proxy"
+	"strings"
 )
 
-// probeTCPViaSocks5 tries to open a TCP connection via the given SOCKS5 proxy
-// to targetAddr (e.g. "smtp.gmail.com:587"). If we get a TCP handshake within
-// timeout, we consider that capability allowed.
-func probeTCPViaSocks5(ctx context.Context, proxyHost string, proxyPort int, user, pass string, targetAddr string) bool {
-	socksAddr := net.JoinHostPort(proxyHost, strconv.Itoa(proxyPort))
+// contextDialer opens TCP connections through a proxy tunnel.
+type contextDialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// maxBannerLen bounds the greeting we keep in the result.
+const maxBannerLen = 200
+
+// mailResult is what a protocol-level mail probe learned about one target.
+type mailResult struct {
+	Banner   string // first line of the server greeting
+	StartTLS bool   // SMTP only: EHLO advertised STARTTLS
+}
+
+// mailConn is a tunnelled connection to a mail server, optionally wrapped
+// in TLS (implicit TLS ports 465/993/995).
+type mailConn struct {
+	net.Conn
+	r *bufio.Reader
+}
 
-	var auth *proxy.Auth
-	if user != "" || pass != "" {
-		auth = &proxy.Auth{User: user, Password: pass}
+// dialMail opens a tunnel to addr through d and, for tlsFirst ports,
+// completes the TLS handshake before any protocol bytes are read.
+// A TCP handshake alone proves nothing: transparent firewalls often
+// accept it and then drop the connection, so callers must read a banner.
+func dialMail(ctx context.Context, d contextDialer, addr string, tlsFirst bool) (*mailConn, func() error, error) {
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	dialer, err := proxy.SOCKS5("tcp", socksAddr, auth, &net.Dialer{
-		Timeout:   4 * time.Second,
-		KeepAlive: 30 * time.Second,
-	})
+	if tlsFirst {
+		host, _, _ := net.SplitHostPort(addr)
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("tls handshake: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	stop := watchConnContext(ctx, conn)
+	return &mailConn{Conn: conn, r: bufio.NewReader(conn)}, stop, nil
+}
+
+func (c *mailConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
 	if err != nil {
-		return false
-	}
-
-	// context-aware dial wrapper
-	type d interface {
-		Dial(network, addr string) (net.Conn, error)
-	}
-	sd, ok := dialer.(d)
-	if !ok {
-		return false
-	}
-
-	// we'll emulate context timeout manually:
-	done := make(chan bool, 1)
-	var conn net.Conn
-	var dialErr error
-	go func() {
-		c, err := sd.Dial("tcp", targetAddr)
-		conn = c
-		dialErr = err
-		done <- true
-	}()
-
-	select {
-	case <-ctx.Done():
-		return false
-	case <-done:
-		if dialErr != nil {
-			return false
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readSMTPReply reads a (possibly multi-line) SMTP reply and returns its
+// code and lines. Continuation lines look like "250-..." and the last
+// line "250 ...".
+func (c *mailConn) readSMTPReply() (string, []string, error) {
+	var lines []string
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return "", lines, err
+		}
+		if len(line) < 3 {
+			return "", lines, fmt.Errorf("malformed smtp reply %q", line)
 		}
-		if conn != nil {
-			_ = conn.Close()
+		lines = append(lines, line)
+		if len(line) == 3 || line[3] != '-' {
+			return line[:3], lines, nil
 		}
-		return true
 	}
 }
+
+// probeSMTP expects a 220 greeting, sends EHLO and checks whether the
+// server offers STARTTLS (only meaningful on cleartext ports).
+func probeSMTP(ctx context.Context, d contextDialer, addr string, tlsFirst bool) (mailResult, error) {
+	c, stop, err := dialMail(ctx, d, addr, tlsFirst)
+	if err != nil {
+		return mailResult{}, err
+	}
+	defer c.Close()
+	defer stop()
+
+	code, lines, err := c.readSMTPReply()
+	if err != nil {
+		return mailResult{}, fmt.Errorf("read smtp greeting: %w", err)
+	}
+	if code != "220" {
+		return mailResult{}, fmt.Errorf("unexpected smtp greeting %q", lines[0])
+	}
+	res := mailResult{Banner: trimBanner(lines[0])}
+
+	if _, err := fmt.Fprintf(c, "EHLO proxycheck.invalid\r\n"); err != nil {
+		return mailResult{}, err
+	}
+	code, lines, err = c.readSMTPReply()
+	if err != nil {
+		return mailResult{}, fmt.Errorf("read ehlo reply: %w", err)
+	}
+	if code != "250" {
+		return mailResult{}, fmt.Errorf("ehlo rejected: %q", lines[0])
+	}
+	for _, l := range lines[1:] {
+		if len(l) > 4 && strings.EqualFold(strings.TrimSpace(l[4:]), "STARTTLS") {
+			res.StartTLS = true
+		}
+	}
+
+	fmt.Fprintf(c, "QUIT\r\n")
+	return res, nil
+}
+
+// probePOP3 expects a "+OK" greeting.
+func probePOP3(ctx context.Context, d contextDialer, addr string, tlsFirst bool) (mailResult, error) {
+	return probeGreeting(ctx, d, addr, tlsFirst, "+OK", "QUIT\r\n")
+}
+
+// probeIMAP expects an untagged "* OK" greeting.
+func probeIMAP(ctx context.Context, d contextDialer, addr string, tlsFirst bool) (mailResult, error) {
+	return probeGreeting(ctx, d, addr, tlsFirst, "* OK", "a1 LOGOUT\r\n")
+}
+
+// mailTarget is one host:port to try for a mail protocol.
+type mailTarget struct {
+	addr     string
+	tlsFirst bool // implicit TLS (465/993/995)
+}
+
+var (
+	smtpTargets = []mailTarget{{"smtp.gmail.com:587", false}, {"smtp.gmail.com:465", true}}
+	pop3Targets = []mailTarget{{"pop.gmail.com:995", true}, {"pop.gmail.com:110", false}}
+	imapTargets = []mailTarget{{"imap.gmail.com:993", true}, {"imap.gmail.com:143", false}}
+)
+
+// firstMailTarget runs probe against targets in order and returns the
+// result and port of the first one that passes.
+func firstMailTarget(ctx context.Context, d contextDialer, targets []mailTarget,
+	probe func(context.Context, contextDialer, string, bool) (mailResult, error)) (mailResult, int, bool) {
+	for _, t := range targets {
+		res, err := probe(ctx, d, t.addr, t.tlsFirst)
+		if err != nil {
+			continue
+		}
+		_, portStr, _ := net.SplitHostPort(t.addr)
+		port, _ := strconv.Atoi(portStr)
+		return res, port, true
+	}
+	return mailResult{}, 0, false
+}
+
+// probeGreeting reads one greeting line, checks its prefix and says goodbye.
+func probeGreeting(ctx context.Context, d contextDialer, addr string, tlsFirst bool, prefix, bye string) (mailResult, error) {
+	c, stop, err := dialMail(ctx, d, addr, tlsFirst)
+	if err != nil {
+		return mailResult{}, err
+	}
+	defer c.Close()
+	defer stop()
+
+	line, err := c.readLine()
+	if err != nil {
+		return mailResult{}, fmt.Errorf("read greeting: %w", err)
+	}
+	if !strings.HasPrefix(strings.ToUpper(line), prefix) {
+		return mailResult{}, fmt.Errorf("unexpected greeting %q", line)
+	}
+
+	fmt.Fprint(c, bye)
+	return mailResult{Banner: trimBanner(line)}, nil
+}
+
+func trimBanner(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > maxBannerLen {
+		s = s[:maxBannerLen]
+	}
+	return s
+}
@@ -0,0 +1,125 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+// NewProxyTLSConfig builds the TLS config used to handshake with
+// TLS-wrapped proxy endpoints. caFile (PEM) replaces the system roots
+// when set; insecure disables certificate verification entirely.
+func NewProxyTLSConfig(caFile string, insecure bool) (*tls.Config, error) {
+	conf := &tls.Config{
+		InsecureSkipVerify: insecure,
+	}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read proxy ca bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		conf.RootCAs = pool
+	}
+
+	return conf, nil
+}
+
+// proxyDialer opens the raw connection to the proxy endpoint itself,
+// wrapping it in TLS for TLS-only proxies. The protocol handshake
+// (SOCKS, CONNECT) is done by the caller on top of it.
+type proxyDialer struct {
+	addr      string
+	tlsConfig *tls.Config // nil for cleartext proxies
+
+	mu   sync.Mutex
+	cert *model.CertInfo // proxy certificate from the last TLS handshake
+}
+
+// newProxyDialer returns a dialer for p. TLS is used for "https" proxies,
+// as curl and http.ProxyURL do, when the proxy line asked for it
+// (scheme+tls://) or --proxy-tls is set for the whole batch.
+func newProxyDialer(p model.ProxyInput, cfg model.Config) *proxyDialer {
+	pd := &proxyDialer{
+		addr: net.JoinHostPort(p.Host, strconv.Itoa(p.Port)),
+	}
+	if !p.TLS && !cfg.ProxyTLS && proxyType(p, cfg) != "https" {
+		return pd
+	}
+
+	if cfg.ProxyTLSConfig != nil {
+		pd.tlsConfig = cfg.ProxyTLSConfig.Clone()
+	} else {
+		pd.tlsConfig = &tls.Config{}
+	}
+	pd.tlsConfig.ServerName = p.Host
+	if cfg.ProxyTLSServerName != "" {
+		pd.tlsConfig.ServerName = cfg.ProxyTLSServerName
+	}
+	return pd
+}
+
+// DialContext connects to the proxy. network and addr are ignored: the
+// signature only exists so the dialer plugs into http.Transport, which
+// always dials the proxy address when Transport.Proxy is set.
+func (pd *proxyDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	nd := net.Dialer{
+		Timeout:   5 * time.Second, // base dial timeout; final timeout enforced by ctx too
+		KeepAlive: 30 * time.Second,
+	}
+	markPhase(ctx, phaseDialStart)
+	conn, err := nd.DialContext(ctx, "tcp", pd.addr)
+	if err != nil {
+		return nil, err
+	}
+	markPhase(ctx, phaseProxyConnected)
+	if pd.tlsConfig == nil {
+		return conn, nil
+	}
+
+	tlsConn := tls.Client(conn, pd.tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy tls handshake: %w", err)
+	}
+	markPhase(ctx, phaseProxyTLSDone)
+
+	if certs := tlsConn.ConnectionState().PeerCertificates; len(certs) > 0 {
+		pd.mu.Lock()
+		pd.cert = certInfo(certs[0])
+		pd.mu.Unlock()
+	}
+	return tlsConn, nil
+}
+
+// peerCert returns the proxy certificate seen on the last TLS handshake,
+// or nil for cleartext proxies.
+func (pd *proxyDialer) peerCert() *model.CertInfo {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+	return pd.cert
+}
+
+func certInfo(c *x509.Certificate) *model.CertInfo {
+	if c == nil {
+		return nil
+	}
+	return &model.CertInfo{
+		Subject:  c.Subject.String(),
+		Issuer:   c.Issuer.String(),
+		NotAfter: c.NotAfter,
+		Pin:      spkiPin(c),
+	}
+}
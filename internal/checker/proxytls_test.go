@@ -0,0 +1,110 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/August26/proxycheck-go/internal/judge"
+	"github.com/August26/proxycheck-go/internal/model"
+	"github.com/August26/proxycheck-go/internal/parser"
+)
+
+// startTLSEndpoint accepts connections and reports on handshakes
+// whether a TLS handshake came in.
+func startTLSEndpoint(t *testing.T) (port int, handshakes chan bool) {
+	t.Helper()
+	cert, _, err := judge.SelfSignedCert([]string{"127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	handshakes = make(chan bool, 1)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			// a ClientHello starts with a handshake record (0x16)
+			head := make([]byte, 1)
+			if _, err := conn.Read(head); err != nil || head[0] != 0x16 {
+				handshakes <- false
+				conn.Close()
+				continue
+			}
+			tlsConn := tls.Server(&prefixConn{Conn: conn, prefix: head}, &tls.Config{Certificates: []tls.Certificate{cert}})
+			handshakes <- tlsConn.Handshake() == nil
+			tlsConn.Close()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, handshakes
+}
+
+type prefixConn struct {
+	net.Conn
+	prefix []byte
+}
+
+func (c *prefixConn) Read(b []byte) (int, error) {
+	if len(c.prefix) > 0 {
+		n := copy(b, c.prefix)
+		c.prefix = c.prefix[n:]
+		return n, nil
+	}
+	return c.Conn.Read(b)
+}
+
+func TestProxyDialerTLS(t *testing.T) {
+	port, handshakes := startTLSEndpoint(t)
+	addr := "127.0.0.1:" + strconv.Itoa(port)
+	insecure := &tls.Config{InsecureSkipVerify: true}
+
+	cases := []struct {
+		line    string
+		cfg     model.Config
+		wantTLS bool
+	}{
+		{"https://" + addr, model.Config{ProxyType: "socks5"}, true},
+		{"http://" + addr, model.Config{ProxyType: "socks5"}, false},
+		{"socks5+tls://" + addr, model.Config{ProxyType: "socks5"}, true},
+		{addr, model.Config{ProxyType: "https"}, true},
+		{addr, model.Config{ProxyType: "http"}, false},
+		{addr, model.Config{ProxyType: "http", ProxyTLS: true}, true},
+	}
+	for _, c := range cases {
+		path := filepath.Join(t.TempDir(), "proxies.txt")
+		if err := os.WriteFile(path, []byte(c.line+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		proxies, err := parser.LoadFromFile(path)
+		if err != nil || len(proxies) != 1 {
+			t.Fatalf("%s: parse: %v %+v", c.line, err, proxies)
+		}
+
+		c.cfg.ProxyTLSConfig = insecure
+		pd := newProxyDialer(proxies[0], c.cfg)
+		conn, err := pd.DialContext(context.Background(), "tcp", "")
+		if err == nil {
+			if !c.wantTLS {
+				conn.Write([]byte("CONNECT "))
+			}
+			conn.Close()
+		}
+		if got := <-handshakes; got != c.wantTLS {
+			t.Errorf("%s (--type %s): tls handshake %v, want %v (dial err: %v)", c.line, c.cfg.ProxyType, got, c.wantTLS, err)
+		}
+		if c.wantTLS && pd.peerCert() == nil {
+			t.Errorf("%s: proxy certificate not recorded", c.line)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package checker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+// defaultSessionFormat is the session username scheme most residential
+// gateways accept ("user-session-abc123").
+const defaultSessionFormat = "{user}-session-{session}"
+
+// rotationSample is one judge request made while profiling rotation.
+type rotationSample struct {
+	ip  string
+	at  time.Time
+	geo model.GeoInfo
+}
+
+// profileRotation measures how a gateway assigns exit IPs. It makes
+// cfg.RotationRequests judge requests, each on a fresh connection:
+//
+//   - first with p's own credentials, to see how often the exit IP changes
+//     (per-connection rotation) and how spread out the exits are;
+//   - then, if p has a username, with a fixed session ID appended to it,
+//     to see whether and for how long the gateway keeps that session on
+//     one exit IP. cfg.RotationInterval spaces the requests, so N requests
+//     cover N-1 intervals of stickiness.
+func profileRotation(ctx context.Context, p model.ProxyInput, cfg model.Config) *model.RotationProfile {
+	prof := &model.RotationProfile{
+		Requests:  cfg.RotationRequests,
+		Countries: map[string]int{},
+		ASNs:      map[string]int{},
+	}
+
+	samples := rotationSamples(ctx, p, cfg)
+	prof.Succeeded = len(samples)
+
+	seen := map[string]bool{}
+	changes := 0
+	for i, s := range samples {
+		if !seen[s.ip] {
+			seen[s.ip] = true
+			prof.ExitIPs = append(prof.ExitIPs, s.ip)
+		}
+		if i > 0 && s.ip != samples[i-1].ip {
+			changes++
+		}
+		if s.geo.Country != "" {
+			prof.Countries[s.geo.Country]++
+		}
+		if s.geo.ASN != "" {
+			prof.ASNs[s.geo.ASN]++
+		}
+	}
+	if len(samples) > 1 {
+		prof.RotationRate = float64(changes) / float64(len(samples)-1)
+	}
+
+	if p.Username == "" {
+		return prof
+	}
+
+	sp := p
+	sp.Username = sessionUsername(cfg.SessionFormat, p.Username, newSessionID())
+	session := rotationSamples(ctx, sp, cfg)
+	prof.SessionRequests = cfg.RotationRequests
+	if len(session) == 0 {
+		return prof
+	}
+
+	prof.StickyHeld = true
+	last := session[0]
+	for _, s := range session[1:] {
+		if s.ip != session[0].ip {
+			prof.StickyHeld = false
+			break
+		}
+		last = s
+	}
+	prof.StickyDurationMs = last.at.Sub(session[0].at).Milliseconds()
+
+	return prof
+}
+
+// rotationSamples makes cfg.RotationRequests judge requests through p,
+// each on a new connection, and returns the successful ones.
+func rotationSamples(ctx context.Context, p model.ProxyInput, cfg model.Config) []rotationSample {
+	judgeURL, _ := judgeURLs(cfg)
+
+	var out []rotationSample
+	for i := 0; i < cfg.RotationRequests; i++ {
+		if i > 0 && cfg.RotationInterval > 0 {
+			select {
+			case <-ctx.Done():
+				return out
+			case <-time.After(cfg.RotationInterval):
+			}
+		}
+
+		// A new client per request: keep-alive would pin us to one exit.
+		client := buildClientForProxy(p, newProxyDialer(p, cfg), cfg)
+		reqCtx, cancel := context.WithTimeout(ctx, time.Duration(cfg.TimeoutSeconds)*time.Second)
+		hb, err := fetchJudge(reqCtx, client, judgeURL)
+		cancel()
+		client.CloseIdleConnections()
+		if err != nil {
+			continue
+		}
+
+		s := rotationSample{ip: firstIPToken(hb.Origin), at: time.Now()}
+		if s.ip == "" {
+			continue
+		}
+		if cfg.Resolver != nil {
+			s.geo, _ = cfg.Resolver.Lookup(s.ip)
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// sessionUsername fills {user} and {session} in format.
+func sessionUsername(format, user, session string) string {
+	if format == "" {
+		format = defaultSessionFormat
+	}
+	return strings.NewReplacer("{user}", user, "{session}", session).Replace(format)
+}
+
+func newSessionID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
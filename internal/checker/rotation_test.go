@@ -0,0 +1,139 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+func TestSessionUsername(t *testing.T) {
+	cases := []struct {
+		format, user, session string
+		want                  string
+	}{
+		{"", "alice", "ab12", "alice-session-ab12"},
+		{"{user}-session-{session}", "alice", "ab12", "alice-session-ab12"},
+		{"{user}_sid_{session}_ttl_30", "alice", "ab12", "alice_sid_ab12_ttl_30"},
+		{"customer-{user}-cc-us-sessid-{session}", "alice", "ab12", "customer-alice-cc-us-sessid-ab12"},
+		{"{session}.{user}.{session}", "alice", "ab12", "ab12.alice.ab12"},
+		{"fixed", "alice", "ab12", "fixed"},
+	}
+	for _, c := range cases {
+		if got := sessionUsername(c.format, c.user, c.session); got != c.want {
+			t.Errorf("%q: got %q, want %q", c.format, got, c.want)
+		}
+	}
+}
+
+// startFakeGateway runs an HTTP forward proxy that answers judge requests
+// itself, with the exit IP exit picks for the proxy username and the
+// number of requests made with it so far.
+func startFakeGateway(t *testing.T, exit func(user string, n int) string) model.ProxyInput {
+	t.Helper()
+	var mu sync.Mutex
+	counts := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := ""
+		if auth := r.Header.Get("Proxy-Authorization"); auth != "" {
+			req := &http.Request{Header: http.Header{"Authorization": {auth}}}
+			user, _, _ = req.BasicAuth()
+		}
+		mu.Lock()
+		n := counts[user]
+		counts[user]++
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"origin": exit(user, n), "headers": map[string]string{}})
+	}))
+	t.Cleanup(srv.Close)
+
+	p := proxyInputFor(t, srv.Listener.Addr())
+	p.Type = "http"
+	return p
+}
+
+func TestProfileRotation(t *testing.T) {
+	pool := []string{"198.51.100.1", "198.51.100.2", "203.0.113.3"}
+	resolver := staticResolver{
+		"198.51.100.1": {Country: "DE", ASN: "AS64500"},
+		"198.51.100.2": {Country: "DE", ASN: "AS64501"},
+		"203.0.113.3":  {Country: "US", ASN: "AS64501"},
+	}
+	rotating := func(_ string, n int) string { return pool[n%len(pool)] }
+	sticky := func(user string, n int) string {
+		if strings.Contains(user, "-session-") {
+			return pool[2]
+		}
+		return pool[n%len(pool)]
+	}
+	static := func(string, int) string { return pool[0] }
+
+	cases := []struct {
+		name        string
+		exit        func(string, int) string
+		user        string
+		wantIPs     []string
+		wantRate    float64
+		wantCountry map[string]int
+		wantASN     map[string]int
+		wantSession int
+		wantSticky  bool
+	}{
+		{
+			name: "per-connection rotation, sticky sessions", exit: sticky, user: "alice",
+			wantIPs: pool, wantRate: 1,
+			wantCountry: map[string]int{"DE": 3, "US": 1}, wantASN: map[string]int{"AS64500": 2, "AS64501": 2},
+			wantSession: 4, wantSticky: true,
+		},
+		{
+			name: "sessions not honored", exit: rotating, user: "alice",
+			wantIPs: pool, wantRate: 1,
+			wantCountry: map[string]int{"DE": 3, "US": 1}, wantASN: map[string]int{"AS64500": 2, "AS64501": 2},
+			wantSession: 4, wantSticky: false,
+		},
+		{
+			name: "static exit", exit: static, user: "alice",
+			wantIPs: pool[:1], wantRate: 0,
+			wantCountry: map[string]int{"DE": 4}, wantASN: map[string]int{"AS64500": 4},
+			wantSession: 4, wantSticky: true,
+		},
+		{
+			name: "no credentials, no session pass", exit: rotating,
+			wantIPs: pool, wantRate: 1,
+			wantCountry: map[string]int{"DE": 3, "US": 1}, wantASN: map[string]int{"AS64500": 2, "AS64501": 2},
+		},
+	}
+	for _, c := range cases {
+		p := startFakeGateway(t, c.exit)
+		if c.user != "" {
+			p.Username, p.Password = c.user, "secret"
+		}
+		cfg := model.Config{
+			TimeoutSeconds:   5,
+			JudgeURL:         "http://judge.test/get",
+			RotationRequests: 4,
+			Resolver:         resolver,
+		}
+
+		prof := profileRotation(context.Background(), p, cfg)
+		if prof.Succeeded != 4 {
+			t.Fatalf("%s: %d of 4 requests succeeded", c.name, prof.Succeeded)
+		}
+		if !slices.Equal(prof.ExitIPs, c.wantIPs) || prof.RotationRate != c.wantRate {
+			t.Errorf("%s: exits %v at rate %v, want %v at %v", c.name, prof.ExitIPs, prof.RotationRate, c.wantIPs, c.wantRate)
+		}
+		if !maps.Equal(prof.Countries, c.wantCountry) || !maps.Equal(prof.ASNs, c.wantASN) {
+			t.Errorf("%s: countries %v, asns %v, want %v, %v", c.name, prof.Countries, prof.ASNs, c.wantCountry, c.wantASN)
+		}
+		if prof.SessionRequests != c.wantSession || prof.StickyHeld != c.wantSticky {
+			t.Errorf("%s: %d session requests, sticky %v, want %d, %v", c.name, prof.SessionRequests, prof.StickyHeld, c.wantSession, c.wantSticky)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package checker
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+// sampleLatencies makes judge requests through p until cfg.Samples have
+// succeeded or 2*cfg.Samples have been tried, and summarizes them. The
+// attempt cap keeps a flaky proxy from being sampled forever while still
+// letting the success ratio show how flaky it is.
+//
+// With cfg.SampleReuse one client (and its keep-alive tunnel) serves every
+// sample, which measures the request path alone: an unrecorded warm-up
+// request opens the tunnel first, so that no sample pays for the dial,
+// proxy handshake and TLS setup. Otherwise every sample dials a new
+// tunnel, which is what short-lived scraper connections see.
+func sampleLatencies(ctx context.Context, p model.ProxyInput, cfg model.Config) *model.LatencySamples {
+	judgeURL, _ := judgeURLs(cfg)
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+
+	client := buildClientForProxy(p, newProxyDialer(p, cfg), cfg)
+	defer func() { client.CloseIdleConnections() }()
+
+	if cfg.SampleReuse {
+		// a failed warm-up leaves the dial to the first sample
+		warmCtx, cancel := context.WithTimeout(ctx, timeout)
+		fetchJudge(warmCtx, client, judgeURL)
+		cancel()
+	}
+
+	out := &model.LatencySamples{}
+	var latencies []float64
+	for out.Attempts < 2*cfg.Samples && len(latencies) < cfg.Samples {
+		if ctx.Err() != nil {
+			break
+		}
+		if !cfg.SampleReuse && out.Attempts > 0 {
+			client.CloseIdleConnections()
+			client = buildClientForProxy(p, newProxyDialer(p, cfg), cfg)
+		}
+		out.Attempts++
+
+		reqCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		_, err := fetchJudge(reqCtx, client, judgeURL)
+		elapsed := time.Since(start)
+		cancel()
+		if err != nil {
+			continue
+		}
+		latencies = append(latencies, float64(elapsed.Microseconds())/1000.0)
+	}
+
+	out.Succeeded = len(latencies)
+	if out.Attempts > 0 {
+		out.SuccessRatio = float64(out.Succeeded) / float64(out.Attempts)
+	}
+	if len(latencies) == 0 {
+		return out
+	}
+
+	out.JitterMs = jitter(latencies)
+
+	sorted := append([]float64(nil), latencies...)
+	sort.Float64s(sorted)
+	out.MinMs = sorted[0]
+	out.MedianMs = percentile(sorted, 50)
+	out.P95Ms = percentile(sorted, 95)
+	out.MaxMs = sorted[len(sorted)-1]
+	return out
+}
+
+// percentile returns the nearest-rank pct-th percentile of sorted values.
+func percentile(sorted []float64, pct float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(pct / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// jitter is the mean absolute difference between consecutive samples,
+// in the order they were taken (as in RFC 3550's interarrival jitter,
+// without the smoothing).
+func jitter(latencies []float64) float64 {
+	if len(latencies) < 2 {
+		return 0
+	}
+	var sum float64
+	for i := 1; i < len(latencies); i++ {
+		sum += math.Abs(latencies[i] - latencies[i-1])
+	}
+	return sum / float64(len(latencies)-1)
+}
@@ -0,0 +1,79 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/August26/proxycheck-go/internal/judge"
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+
+	cases := []struct {
+		pct  float64
+		want float64
+	}{
+		{50, 50},
+		{95, 100},
+		{0, 10},
+		{100, 100},
+	}
+	for _, c := range cases {
+		if got := percentile(sorted, c.pct); got != c.want {
+			t.Errorf("p%v: got %v, want %v", c.pct, got, c.want)
+		}
+	}
+}
+
+func TestJitter(t *testing.T) {
+	if got := jitter([]float64{100, 120, 100, 140}); got != 80.0/3 {
+		t.Errorf("got %v, want %v", got, 80.0/3)
+	}
+	if got := jitter([]float64{100}); got != 0 {
+		t.Errorf("single sample: got %v, want 0", got)
+	}
+}
+
+func TestSampleLatenciesReuse(t *testing.T) {
+	cases := []struct {
+		reuse               bool
+		wantConns, wantReqs int64
+	}{
+		// one tunnel, opened by a warm-up request no sample pays for
+		{true, 1, 4},
+		{false, 3, 3},
+	}
+	for _, c := range cases {
+		var conns, reqs atomic.Int64
+		handler := judge.NewHandler()
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqs.Add(1)
+			handler.ServeHTTP(w, r)
+		}))
+		srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				conns.Add(1)
+			}
+		}
+		srv.Start()
+
+		p := startResolvingSOCKS5(t, "127.0.0.1:1")
+		p.Type = "socks5"
+		cfg := model.Config{TimeoutSeconds: 5, JudgeURL: srv.URL + "/get", Samples: 3, SampleReuse: c.reuse}
+
+		out := sampleLatencies(context.Background(), p, cfg)
+		srv.Close()
+		if out.Attempts != 3 || out.Succeeded != 3 {
+			t.Errorf("reuse=%v: %d of %d attempts succeeded, want 3 of 3", c.reuse, out.Succeeded, out.Attempts)
+		}
+		if conns.Load() != c.wantConns || reqs.Load() != c.wantReqs {
+			t.Errorf("reuse=%v: %d connections, %d requests, want %d, %d", c.reuse, conns.Load(), reqs.Load(), c.wantConns, c.wantReqs)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package checker
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+// ------------------------------------------------------------------------------------
+// SOCKS4 / SOCKS4a proxy checker implementation
+// ------------------------------------------------------------------------------------
+
+// checkSOCKS4 checks a SOCKS4 proxy, or a SOCKS4a proxy when remoteDNS is set.
+func checkSOCKS4(ctx context.Context, p model.ProxyInput, cfg model.Config, remoteDNS bool) model.ProxyCheckResult {
+	pd := newProxyDialer(p, cfg)
+	client := buildSOCKS4HTTPClient(p, pd, cfg, remoteDNS)
+
+	out := checkThroughClient(ctx, p, client, cfg)
+	out.ProxyCert = pd.peerCert()
+	return out
+}
+
+// buildSOCKS4HTTPClient builds an *http.Client whose connections are
+// opened through a SOCKS4(a) proxy.
+func buildSOCKS4HTTPClient(p model.ProxyInput, pd *proxyDialer, cfg model.Config, remoteDNS bool) *http.Client {
+	dialer := &socks4Dialer{
+		proxy:     pd,
+		userID:    p.Username,
+		remoteDNS: remoteDNS,
+	}
+
+	transport := newTransport(cfg)
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+	}
+}
+
+// socks4Dialer opens TCP connections through a SOCKS4 proxy.
+//
+// SOCKS4 only carries IPv4 destinations, so hostnames are resolved locally.
+// With remoteDNS (SOCKS4a) the hostname is sent to the proxy instead.
+// SOCKS4 has no password; the username goes into the USERID field.
+type socks4Dialer struct {
+	proxy     *proxyDialer
+	userID    string
+	remoteDNS bool
+}
+
+// SOCKS4 reply codes (CD field of the reply).
+const (
+	socks4Granted        = 0x5A
+	socks4Rejected       = 0x5B
+	socks4IdentdDown     = 0x5C
+	socks4IdentdMismatch = 0x5D
+)
+
+var (
+	errSOCKS4Rejected       = errors.New("socks4: request rejected or failed")
+	errSOCKS4IdentdDown     = errors.New("socks4: rejected, identd unreachable")
+	errSOCKS4IdentdMismatch = errors.New("socks4: rejected, userid mismatch")
+)
+
+// DialContext connects to addr through the proxy. It honors ctx for both
+// the TCP dial and the handshake.
+func (d *socks4Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if network != "tcp" && network != "tcp4" {
+		return nil, fmt.Errorf("socks4: unsupported network %q", network)
+	}
+
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 0xffff {
+		return nil, fmt.Errorf("socks4: invalid port %q", portStr)
+	}
+
+	req := []byte{0x04, 0x01, 0, 0}
+	binary.BigEndian.PutUint16(req[2:], uint16(port))
+
+	var hostname string
+	ip := net.ParseIP(host).To4()
+	switch {
+	case ip != nil:
+		req = append(req, ip...)
+	case d.remoteDNS:
+		// 0.0.0.x with x != 0 tells a SOCKS4a server to read the
+		// hostname that follows the USERID.
+		req = append(req, 0, 0, 0, 1)
+		hostname = host
+	default:
+		ips, err := net.DefaultResolver.LookupIP(ctx, "ip4", host)
+		if err != nil {
+			return nil, err
+		}
+		req = append(req, ips[0].To4()...)
+	}
+
+	req = append(req, d.userID...)
+	req = append(req, 0x00)
+	if hostname != "" {
+		req = append(req, hostname...)
+		req = append(req, 0x00)
+	}
+
+	conn, err := d.proxy.DialContext(ctx, "tcp", "")
+	if err != nil {
+		return nil, err
+	}
+
+	stop := watchConnContext(ctx, conn)
+	err = socks4Handshake(conn, req)
+	if stopErr := stop(); err == nil {
+		err = stopErr
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	markPhase(ctx, phaseTunnelUp)
+	return conn, nil
+}
+
+func socks4Handshake(conn net.Conn, req []byte) error {
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	// reply: VN=0x00 CD DSTPORT(2) DSTIP(4)
+	reply := make([]byte, 8)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != 0x00 && reply[0] != 0x04 {
+		return fmt.Errorf("socks4: invalid reply version 0x%02x", reply[0])
+	}
+
+	switch reply[1] {
+	case socks4Granted:
+		return nil
+	case socks4Rejected:
+		return errSOCKS4Rejected
+	case socks4IdentdDown:
+		return errSOCKS4IdentdDown
+	case socks4IdentdMismatch:
+		return errSOCKS4IdentdMismatch
+	default:
+		return fmt.Errorf("socks4: unknown reply code 0x%02x", reply[1])
+	}
+}
+
+// watchConnContext applies ctx's deadline to conn and aborts blocked
+// I/O if ctx is cancelled. The returned stop func clears the deadline
+// and reports ctx.Err() if the context fired during the handshake.
+func watchConnContext(ctx context.Context, conn net.Conn) (stop func() error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	done := make(chan struct{})
+	exited := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Unix(1, 0))
+			exited <- true
+		case <-done:
+			exited <- false
+		}
+	}()
+
+	return func() error {
+		close(done)
+		if <-exited {
+			return ctx.Err()
+		}
+		conn.SetDeadline(time.Time{})
+		return nil
+	}
+}
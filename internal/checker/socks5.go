@@ -0,0 +1,334 @@
+package checker
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+// SOCKS5 commands (RFC 1928 section 4).
+const (
+	socks5CmdConnect      = 0x01
+	socks5CmdUDPAssociate = 0x03
+)
+
+// SOCKS5 address types.
+const (
+	socks5AtypIPv4   = 0x01
+	socks5AtypDomain = 0x03
+	socks5AtypIPv6   = 0x04
+)
+
+// SOCKS5 authentication methods.
+const (
+	socks5AuthNone         = 0x00
+	socks5AuthUserPass     = 0x02
+	socks5AuthNoAcceptable = 0xFF
+)
+
+var (
+	// errSOCKS5AuthRequired: the proxy accepts none of the methods we can offer,
+	// typically because it wants credentials and we have none.
+	errSOCKS5AuthRequired = errors.New("socks5: no acceptable authentication method")
+	// errSOCKS5AuthFailed: the proxy rejected our username/password.
+	errSOCKS5AuthFailed = errors.New("socks5: authentication failed")
+)
+
+// socks5ReplyError is a non-zero REP field in a SOCKS5 reply.
+type socks5ReplyError struct {
+	Code byte
+}
+
+var socks5ReplyText = map[byte]string{
+	0x01: "general SOCKS server failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+}
+
+func (e *socks5ReplyError) Error() string {
+	if text, ok := socks5ReplyText[e.Code]; ok {
+		return fmt.Sprintf("socks5: %s (REP 0x%02x)", text, e.Code)
+	}
+	return fmt.Sprintf("socks5: unknown reply code 0x%02x", e.Code)
+}
+
+// socks5Dialer is a context-aware SOCKS5 client. Every network operation,
+// from the TCP dial to the last reply byte, is bound to the caller's ctx,
+// so an expired or cancelled check never leaves a goroutine or socket behind.
+//
+// Like curl's socks5://, target hostnames are resolved locally and the proxy
+// gets an IP address; remoteDNS sends the hostname instead (socks5h://).
+type socks5Dialer struct {
+	proxy     *proxyDialer
+	username  string
+	password  string
+	remoteDNS bool
+}
+
+// newSOCKS5Dialer returns a dialer for p that reaches the proxy through pd.
+func newSOCKS5Dialer(p model.ProxyInput, pd *proxyDialer) *socks5Dialer {
+	return &socks5Dialer{
+		proxy:    pd,
+		username: p.Username,
+		password: p.Password,
+	}
+}
+
+// DialContext opens a TCP connection to addr through the proxy.
+func (d *socks5Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("socks5: unsupported network %q", network)
+	}
+
+	target, err := d.resolveTarget(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := d.proxy.DialContext(ctx, "tcp", "")
+	if err != nil {
+		return nil, err
+	}
+
+	stop := watchConnContext(ctx, conn)
+	err = d.negotiate(conn)
+	if err == nil {
+		_, err = d.request(conn, socks5CmdConnect, target)
+	}
+	if stopErr := stop(); err == nil {
+		err = stopErr
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	markPhase(ctx, phaseTunnelUp)
+	return conn, nil
+}
+
+// associateUDP opens a control connection and issues UDP ASSOCIATE. The
+// caller owns conn: the relay lives only as long as it stays open.
+// relay is the BND.ADDR/BND.PORT the proxy told us to send datagrams to.
+func (d *socks5Dialer) associateUDP(ctx context.Context) (conn net.Conn, relay string, err error) {
+	conn, err = d.proxy.DialContext(ctx, "tcp", "")
+	if err != nil {
+		return nil, "", err
+	}
+
+	stop := watchConnContext(ctx, conn)
+	err = d.negotiate(conn)
+	if err == nil {
+		// We don't know our own UDP source yet, so ask for 0.0.0.0:0.
+		relay, err = d.request(conn, socks5CmdUDPAssociate, "0.0.0.0:0")
+	}
+	if stopErr := stop(); err == nil {
+		err = stopErr
+	}
+	if err != nil {
+		conn.Close()
+		return nil, "", err
+	}
+	return conn, relay, nil
+}
+
+// resolveTarget resolves addr's host locally unless remoteDNS is set.
+func (d *socks5Dialer) resolveTarget(ctx context.Context, addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if d.remoteDNS || net.ParseIP(host) != nil {
+		return addr, nil
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	ip := ips[0].IP
+	for _, cand := range ips {
+		if cand.IP.To4() != nil {
+			ip = cand.IP
+			break
+		}
+	}
+	return net.JoinHostPort(ip.String(), port), nil
+}
+
+// negotiate runs the method selection and, if chosen, RFC 1929
+// username/password authentication.
+func (d *socks5Dialer) negotiate(conn net.Conn) error {
+	// 1. greeting
+	// build methods:
+	//   0x00 = no auth
+	//   0x02 = username/password
+	methods := []byte{socks5AuthNone}
+	useAuth := d.username != "" || d.password != ""
+	if useAuth {
+		methods = append(methods, socks5AuthUserPass)
+	}
+
+	req := []byte{0x05, byte(len(methods))}
+	req = append(req, methods...)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	// server chooses method
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return err
+	}
+	if buf[0] != 0x05 {
+		return fmt.Errorf("socks5: invalid version 0x%02x in method reply", buf[0])
+	}
+
+	// 2. auth if required
+	switch buf[1] {
+	case socks5AuthNone:
+		return nil
+	case socks5AuthUserPass:
+		if !useAuth {
+			return errSOCKS5AuthRequired
+		}
+		return socks5UserPassAuth(conn, d.username, d.password)
+	case socks5AuthNoAcceptable:
+		return errSOCKS5AuthRequired
+	default:
+		// proxy requested something we don't support
+		return fmt.Errorf("socks5: unsupported auth method 0x%02x", buf[1])
+	}
+}
+
+// request sends cmd for addr and returns BND.ADDR:BND.PORT from the reply.
+func (d *socks5Dialer) request(conn net.Conn, cmd byte, addr string) (string, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 0 || port > 0xffff {
+		return "", fmt.Errorf("socks5: invalid port %q", portStr)
+	}
+
+	req := []byte{0x05, cmd, 0x00}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(req, socks5AtypIPv4)
+			req = append(req, ip4...)
+		} else {
+			req = append(req, socks5AtypIPv6)
+			req = append(req, ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return "", fmt.Errorf("socks5: hostname too long: %q", host)
+		}
+		req = append(req, socks5AtypDomain, byte(len(host)))
+		req = append(req, host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+
+	if _, err := conn.Write(req); err != nil {
+		return "", err
+	}
+
+	// reply: VER REP RSV ATYP BND.ADDR BND.PORT
+	hdr := make([]byte, 4)
+	if _, err := io.ReadFull(conn, hdr); err != nil {
+		return "", err
+	}
+	if hdr[0] != 0x05 {
+		return "", fmt.Errorf("socks5: invalid version 0x%02x in reply", hdr[0])
+	}
+	if hdr[1] != 0x00 {
+		return "", &socks5ReplyError{Code: hdr[1]}
+	}
+
+	return readSOCKS5Addr(conn, hdr[3])
+}
+
+// readSOCKS5Addr reads an ADDR/PORT pair of the given ATYP and returns it
+// as host:port.
+func readSOCKS5Addr(r io.Reader, atyp byte) (string, error) {
+	var host string
+	switch atyp {
+	case socks5AtypIPv4:
+		b := make([]byte, net.IPv4len)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return "", err
+		}
+		host = net.IP(b).String()
+	case socks5AtypIPv6:
+		b := make([]byte, net.IPv6len)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return "", err
+		}
+		host = net.IP(b).String()
+	case socks5AtypDomain:
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(r, l); err != nil {
+			return "", err
+		}
+		b := make([]byte, l[0])
+		if _, err := io.ReadFull(r, b); err != nil {
+			return "", err
+		}
+		host = string(b)
+	default:
+		return "", fmt.Errorf("socks5: unknown address type 0x%02x", atyp)
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(r, port); err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), nil
+}
+
+func socks5UserPassAuth(conn net.Conn, username, password string) error {
+	// Username/Password auth subnegotiation per RFC1929.
+	// auth packet:
+	// VER=0x01, ULEN, U, PLEN, P
+	ulen := len(username)
+	plen := len(password)
+	if ulen > 255 || plen > 255 {
+		return errors.New("username/password too long for socks5 auth")
+	}
+	req := []byte{
+		0x01,
+		byte(ulen),
+	}
+	req = append(req, []byte(username)...)
+	req = append(req, byte(plen))
+	req = append(req, []byte(password)...)
+
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	resp := make([]byte, 2)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return err
+	}
+	if resp[0] != 0x01 {
+		return errors.New("invalid auth response version")
+	}
+	if resp[1] != 0x00 {
+		return errSOCKS5AuthFailed
+	}
+	return nil
+}
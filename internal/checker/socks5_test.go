@@ -0,0 +1,108 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+// startFakeSOCKS5 accepts one connection, answers the greeting with
+// "no auth" and replies to the request with rep.
+func startFakeSOCKS5(t *testing.T, rep byte) model.ProxyInput {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		greeting := make([]byte, 3)
+		if _, err := io.ReadFull(conn, greeting); err != nil {
+			return
+		}
+		conn.Write([]byte{0x05, 0x00})
+
+		req := make([]byte, 10) // IPv4 CONNECT
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+		conn.Write([]byte{0x05, rep, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+		io.Copy(io.Discard, conn)
+	}()
+
+	return proxyInputFor(t, ln.Addr())
+}
+
+func proxyInputFor(t *testing.T, addr net.Addr) model.ProxyInput {
+	t.Helper()
+	host, portStr, _ := net.SplitHostPort(addr.String())
+	port, _ := strconv.Atoi(portStr)
+	return model.ProxyInput{Host: host, Port: port}
+}
+
+func TestSOCKS5Dialer_ReplyError(t *testing.T) {
+	p := startFakeSOCKS5(t, 0x02)
+	d := newSOCKS5Dialer(p, newProxyDialer(p, model.Config{}))
+
+	_, err := d.DialContext(context.Background(), "tcp", "192.0.2.1:80")
+
+	var repErr *socks5ReplyError
+	if !errors.As(err, &repErr) || repErr.Code != 0x02 {
+		t.Fatalf("expected REP 0x02 error, got %v", err)
+	}
+}
+
+func TestSOCKS5Dialer_Success(t *testing.T) {
+	p := startFakeSOCKS5(t, 0x00)
+	d := newSOCKS5Dialer(p, newProxyDialer(p, model.Config{}))
+
+	conn, err := d.DialContext(context.Background(), "tcp", "192.0.2.1:80")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	conn.Close()
+}
+
+func TestSOCKS5Dialer_HonorsDeadline(t *testing.T) {
+	// A proxy that accepts TCP but never answers the greeting.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+
+	p := proxyInputFor(t, ln.Addr())
+	d := newSOCKS5Dialer(p, newProxyDialer(p, model.Config{}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = d.DialContext(ctx, "tcp", "192.0.2.1:80")
+	if err == nil {
+		t.Fatalf("expected error from silent proxy")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("dial ignored the context deadline, took %v", elapsed)
+	}
+}
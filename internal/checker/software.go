@@ -0,0 +1,307 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+// maxProbeReply bounds how much of a probe's reply is kept for matching.
+const maxProbeReply = 8 << 10
+
+// Where a piece of fingerprint evidence came from.
+const (
+	sourceRequestHeader  = "request header"  // header the judge received through the proxy
+	sourceResponseHeader = "response header" // header the proxy added to a forwarded response
+	sourceErrorPage      = "error page"      // reply to a malformed request
+	sourceConnectError   = "connect error"   // reply to CONNECT to an unresolvable host
+	sourceSOCKS5Method   = "socks5 method"   // method picked from none/GSSAPI/userpass
+)
+
+// softwareEvidence is one thing the proxy sent that may give it away.
+type softwareEvidence struct {
+	source string
+	text   string // "Name: value" for headers, the raw reply for probes
+}
+
+// softwareSignature attributes evidence to a proxy product. Weight is
+// how sure one match makes us (0..1); a "version" group in Pattern is
+// reported as the version.
+type softwareSignature struct {
+	software string
+	sources  []string // evidence sources the pattern applies to; nil means any
+	pattern  *regexp.Regexp
+	weight   float64
+}
+
+var (
+	anySource     []string // nil: the signature applies to every source
+	headerSources = []string{sourceRequestHeader, sourceResponseHeader}
+	probeSources  = []string{sourceErrorPage, sourceConnectError}
+)
+
+var softwareSignatures = []softwareSignature{
+	{"Squid", anySource, regexp.MustCompile(`(?i)\bsquid(?:/(?P<version>\d[\w.]*))?`), 0.9},
+	{"Squid", headerSources, regexp.MustCompile(`(?i)^X-Cache(?:-Lookup)?: (?:HIT|MISS|NONE) from `), 0.6},
+	{"Squid", probeSources, regexp.MustCompile(`^X-Squid-Error: |ERR_(?:INVALID_URL|INVALID_REQ|DNS_FAIL|CONNECT_FAIL|ACCESS_DENIED|CACHE_ACCESS_DENIED)`), 0.8},
+	{"Tinyproxy", anySource, regexp.MustCompile(`(?i)tinyproxy(?:[/ ](?:version )?(?P<version>\d[\w.]*))?`), 0.95},
+	{"3proxy", anySource, regexp.MustCompile(`(?i)\b3proxy\b(?:[ /](?P<version>\d[\w.]*))?`), 0.95},
+	{"3proxy", probeSources, regexp.MustCompile(`Access to requested resource disallowed by administrator`), 0.7},
+	{"Privoxy", anySource, regexp.MustCompile(`(?i)privoxy(?:[ /](?P<version>\d[\w.]*))?`), 0.95},
+	{"MikroTik", anySource, regexp.MustCompile(`(?i)mikrotik(?: httpproxy)?`), 0.95},
+	{"CCProxy", anySource, regexp.MustCompile(`(?i)ccproxy(?:[ /](?P<version>\d[\w.]*))?`), 0.95},
+	{"Polipo", anySource, regexp.MustCompile(`(?i)polipo`), 0.9},
+	{"Apache Traffic Server", anySource, regexp.MustCompile(`(?i)\bATS/(?P<version>\d[\w.]*)|ApacheTrafficServer`), 0.9},
+	{"Varnish", headerSources, regexp.MustCompile(`(?i)^(?:Via: .*varnish|X-Varnish: )`), 0.8},
+	{"nginx", probeSources, regexp.MustCompile(`(?i)^Server: nginx(?:/(?P<version>\d[\w.]*))?|<center>nginx`), 0.6},
+	{"Apache httpd", probeSources, regexp.MustCompile(`(?i)^Server: Apache(?:/(?P<version>\d[\w.]*))?|<address>Apache`), 0.6},
+	{"Dante", []string{sourceSOCKS5Method}, regexp.MustCompile(`^selected 0x01$`), 0.5},
+	{"Zscaler", anySource, regexp.MustCompile(`(?i)zscaler`), 0.95},
+	{"Blue Coat", anySource, regexp.MustCompile(`(?i)bluecoat|blue coat|proxysg`), 0.9},
+	{"FortiGate", anySource, regexp.MustCompile(`(?i)fortigate|fortinet`), 0.9},
+	{"Cloudflare", headerSources, regexp.MustCompile(`(?i)^(?:Cf-Ray: |Server: cloudflare|Cdn-Loop: cloudflare)`), 0.8},
+	{"CloudFront", headerSources, regexp.MustCompile(`(?i)^(?:Via: .*\(CloudFront\)|X-Amz-Cf-Id: )`), 0.9},
+	{"Google Cloud", headerSources, regexp.MustCompile(`(?i)^Via: 1\.1 google$`), 0.8},
+	{"Bright Data", anySource, regexp.MustCompile(`(?i)^X-Luminati-|^X-Brd-|luminati\.io|brightdata`), 0.9},
+}
+
+// softwareMatch is the software a proxy was attributed to.
+type softwareMatch struct {
+	software   string
+	version    string
+	confidence float64  // 1 - product of (1 - weight) over the matching signatures
+	evidence   []string // "source: matched text", one per matching signature
+}
+
+// identifySoftware scores every signature against the evidence and
+// returns the best-scoring product, or a zero match if nothing matched.
+func identifySoftware(evidence []softwareEvidence) softwareMatch {
+	type candidate struct {
+		miss     float64 // product of (1 - weight)
+		version  string
+		evidence []string
+	}
+	candidates := map[string]*candidate{}
+
+	for _, sig := range softwareSignatures {
+		for _, ev := range evidence {
+			if sig.sources != nil && !containsString(sig.sources, ev.source) {
+				continue
+			}
+			matches := sig.pattern.FindAllStringSubmatch(ev.text, -1)
+			if matches == nil {
+				continue
+			}
+			c := candidates[sig.software]
+			if c == nil {
+				c = &candidate{miss: 1}
+				candidates[sig.software] = c
+			}
+			c.miss *= 1 - sig.weight
+
+			// "squid-test (squid/4.10)": the match with a version wins
+			m := matches[0]
+			if i := sig.pattern.SubexpIndex("version"); i > 0 {
+				for _, cand := range matches {
+					if cand[i] != "" {
+						m = cand
+						if c.version == "" {
+							c.version = cand[i]
+						}
+						break
+					}
+				}
+			}
+			// header lines are short enough to quote whole
+			text := strings.TrimSpace(m[0])
+			if !strings.Contains(ev.text, "\n") && len(ev.text) <= 200 {
+				text = ev.text
+			}
+			c.evidence = append(c.evidence, ev.source+": "+text)
+			break // a signature counts once, however often it matches
+		}
+	}
+
+	names := make([]string, 0, len(candidates))
+	for name := range candidates {
+		names = append(names, name)
+	}
+	sort.Strings(names) // deterministic winner on ties
+
+	var best softwareMatch
+	for _, name := range names {
+		c := candidates[name]
+		if conf := 1 - c.miss; conf > best.confidence {
+			best = softwareMatch{software: name, version: c.version, confidence: math.Round(conf*100) / 100, evidence: c.evidence}
+		}
+	}
+	return best
+}
+
+// proxyAnswered reports whether a failed check still got a protocol-level
+// answer from the proxy itself, as opposed to nothing at all.
+func proxyAnswered(class model.ErrorClass) bool {
+	switch class {
+	case model.ErrorClassAuthRequired, model.ErrorClassAuthFailed,
+		model.ErrorClassSOCKSRuleDenied, model.ErrorClassUpstreamHTTP:
+		return true
+	default:
+		return false
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// fingerprintSoftware collects evidence about what software runs p, on
+// top of the headers the judge already saw (res.RawHeaders):
+//   - HTTP(S) proxies: the headers added to a forwarded judge response,
+//     the reply to a request without a host, and to CONNECT to a host
+//     that cannot resolve (.invalid is reserved by RFC 6761);
+//   - SOCKS5: which method the server picks when offered none, GSSAPI
+//     and username/password.
+//
+// Like detectProtocols, every probe opens its own connection and they
+// run in parallel. A probe that fails is simply no evidence.
+func fingerprintSoftware(ctx context.Context, p model.ProxyInput, cfg model.Config, res model.ProxyCheckResult) softwareMatch {
+	var evidence []softwareEvidence
+	for name, value := range res.RawHeaders {
+		evidence = append(evidence, softwareEvidence{sourceRequestHeader, name + ": " + value})
+	}
+
+	type probe struct {
+		source string
+		run    func(net.Conn) string
+	}
+	var probes []probe
+	switch proxyType(p, cfg) {
+	case "http", "https":
+		_, plainJudgeURL := judgeURLs(cfg)
+		auth := proxyAuthHeader(p)
+		probes = []probe{
+			{sourceResponseHeader, func(c net.Conn) string {
+				return probeHTTPReply(c, fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nConnection: close\r\n%s\r\n", plainJudgeURL, urlHost(plainJudgeURL), auth), false)
+			}},
+			{sourceErrorPage, func(c net.Conn) string {
+				return probeHTTPReply(c, "GET / HTTP/1.0\r\n\r\n", true)
+			}},
+			{sourceConnectError, func(c net.Conn) string {
+				const target = "proxycheck-go.invalid:443"
+				return probeHTTPReply(c, fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n%s\r\n", target, target, auth), true)
+			}},
+		}
+	case "socks5", "socks5h":
+		probes = []probe{{sourceSOCKS5Method, probeSOCKS5Method}}
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, pr := range probes {
+		pr := pr
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			probeCtx, cancel := context.WithTimeout(ctx, time.Duration(cfg.TimeoutSeconds)*time.Second)
+			defer cancel()
+			conn, err := newProxyDialer(p, cfg).DialContext(probeCtx, "tcp", "")
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			stop := watchConnContext(probeCtx, conn)
+			defer stop()
+			if deadline, ok := probeCtx.Deadline(); !ok || time.Until(deadline) > detectReadTimeout {
+				conn.SetReadDeadline(time.Now().Add(detectReadTimeout))
+			}
+
+			if reply := pr.run(conn); reply != "" {
+				mu.Lock()
+				evidence = append(evidence, splitReply(pr.source, reply)...)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return identifySoftware(evidence)
+}
+
+// probeHTTPReply writes req and returns the reply's status line and
+// headers and, with withBody, the start of its body.
+func probeHTTPReply(conn net.Conn, req string, withBody bool) string {
+	if _, err := io.WriteString(conn, req); err != nil {
+		return ""
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s\r\n", resp.Proto, resp.Status)
+	resp.Header.Write(&sb)
+	sb.WriteString("\r\n")
+	if withBody {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxProbeReply))
+		sb.Write(body)
+	}
+	return sb.String()
+}
+
+// probeSOCKS5Method offers no auth, GSSAPI and username/password and
+// reports the method the server selects, as "selected 0xNN".
+func probeSOCKS5Method(conn net.Conn) string {
+	if _, err := conn.Write([]byte{0x05, 0x03, socks5AuthNone, 0x01, socks5AuthUserPass}); err != nil {
+		return ""
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil || reply[0] != 0x05 {
+		return ""
+	}
+	return fmt.Sprintf("selected 0x%02x", reply[1])
+}
+
+// splitReply turns a probe reply into evidence: the whole reply, plus
+// one item per header line so header signatures apply to it too.
+func splitReply(source, reply string) []softwareEvidence {
+	out := []softwareEvidence{{source, reply}}
+	head, _, _ := strings.Cut(reply, "\r\n\r\n")
+	lines := strings.Split(head, "\r\n")
+	for _, line := range lines[1:] {
+		if name, value, ok := strings.Cut(line, ":"); ok {
+			out = append(out, softwareEvidence{source, http.CanonicalHeaderKey(strings.TrimSpace(name)) + ": " + strings.TrimSpace(value)})
+		}
+	}
+	return out
+}
+
+func urlHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
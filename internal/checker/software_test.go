@@ -0,0 +1,41 @@
+package checker
+
+import "testing"
+
+func TestIdentifySoftware(t *testing.T) {
+	tinyproxyPage := "HTTP/1.0 400 Bad Request\r\nServer: tinyproxy/1.10.0\r\nContent-Type: text/html\r\n\r\n" +
+		"<html><body><h1>Bad Request</h1><hr /><p><em>Generated by tinyproxy version 1.10.0.</em></p></body></html>"
+	mikrotikPage := "HTTP/1.0 400 Bad Request\r\nServer: Mikrotik HttpProxy\r\n\r\nERROR: bad request"
+
+	cases := []struct {
+		name        string
+		evidence    []softwareEvidence
+		want        string
+		wantVersion string
+		minConf     float64
+	}{
+		{"squid via", []softwareEvidence{
+			{sourceRequestHeader, "Via: 1.1 squid-box (squid/4.10)"},
+			{sourceRequestHeader, "X-Forwarded-For: 203.0.113.7"},
+		}, "Squid", "4.10", 0.9},
+		{"squid via and x-cache", []softwareEvidence{
+			{sourceRequestHeader, "Via: 1.1 box.example (squid/4.10)"},
+			{sourceResponseHeader, "X-Cache: MISS from box.example"},
+		}, "Squid", "4.10", 0.95},
+		{"tinyproxy error page", splitReply(sourceErrorPage, tinyproxyPage), "Tinyproxy", "1.10.0", 0.95},
+		{"mikrotik", splitReply(sourceConnectError, mikrotikPage), "MikroTik", "", 0.95},
+		{"dante method", []softwareEvidence{{sourceSOCKS5Method, "selected 0x01"}}, "Dante", "", 0.5},
+		{"nginx only counts on probes", []softwareEvidence{{sourceResponseHeader, "Server: nginx/1.25.3"}}, "", "", 0},
+		{"nothing", []softwareEvidence{{sourceSOCKS5Method, "selected 0x00"}}, "", "", 0},
+	}
+	for _, c := range cases {
+		got := identifySoftware(c.evidence)
+		if got.software != c.want || got.version != c.wantVersion || got.confidence < c.minConf {
+			t.Errorf("%s: got %q %q (%.2f, %q), want %q %q (>= %.2f)",
+				c.name, got.software, got.version, got.confidence, got.evidence, c.want, c.wantVersion, c.minConf)
+		}
+		if c.want != "" && len(got.evidence) == 0 {
+			t.Errorf("%s: no evidence recorded", c.name)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+// maxTargetBody bounds how much of a target page is read for matching.
+const maxTargetBody = 1 << 20
+
+// LoadTargets reads the site list for per-target reachability checks:
+//
+//	{"targets": [
+//	  {"name": "shop", "url": "https://shop.example.com/", "status": [200], "body": "Add to cart"},
+//	  {"url": "https://api.example.com/health", "body_regex": "\"ok\":\\s*true"}
+//	]}
+//
+// name defaults to the URL host; status defaults to any 2xx. pins
+// (see ParseTLSPins) are checked against the chain of https:// targets.
+func LoadTargets(path string) ([]model.Target, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read targets file: %w", err)
+	}
+
+	var file struct {
+		Targets []model.Target `json:"targets"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse targets file: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for i := range file.Targets {
+		t := &file.Targets[i]
+		u, err := url.Parse(t.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("target #%d: bad url %q", i+1, t.URL)
+		}
+		if t.Name == "" {
+			t.Name = u.Hostname()
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("duplicate target name %q", t.Name)
+		}
+		seen[t.Name] = true
+
+		if t.BodyRegex != "" {
+			re, err := regexp.Compile(t.BodyRegex)
+			if err != nil {
+				return nil, fmt.Errorf("target %q: bad body_regex: %w", t.Name, err)
+			}
+			t.BodyRe = re
+		}
+		for _, pin := range t.Pins {
+			if err := validatePin(pin); err != nil {
+				return nil, fmt.Errorf("target %q: %w", t.Name, err)
+			}
+		}
+	}
+	return file.Targets, nil
+}
+
+// checkTargets fetches every target through p in parallel, each with its
+// own --timeout budget, and returns the results in target order.
+func checkTargets(ctx context.Context, p model.ProxyInput, cfg model.Config) []model.TargetResult {
+	client := buildClientForProxy(p, newProxyDialer(p, cfg), cfg)
+	defer client.CloseIdleConnections()
+
+	out := make([]model.TargetResult, len(cfg.Targets))
+	var wg sync.WaitGroup
+	for i, t := range cfg.Targets {
+		i, t := i, t
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reqCtx, cancel := context.WithTimeout(ctx, time.Duration(cfg.TimeoutSeconds)*time.Second)
+			defer cancel()
+			out[i] = checkTarget(reqCtx, client, t)
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+// checkTarget fetches one target and grades the answer: reachable means
+// the expected status, the expected body and no block page.
+func checkTarget(ctx context.Context, client *http.Client, t model.Target) model.TargetResult {
+	res := model.TargetResult{Name: t.Name, URL: t.URL}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.URL, nil)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	// look like a browser: bare Go clients get blocked for being Go
+	req.Header.Set("User-Agent", browserUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	obs := observeTLS(resp, err)
+	res.TLSIntercepted = obs.intercepted(t.Pins)
+	res.ObservedIssuer = obs.issuer()
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTargetBody))
+	res.LatencyMs = time.Since(start).Milliseconds()
+	res.StatusCode = resp.StatusCode
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	res.Blocked, res.BlockReason = detectBlock(resp, body, t.Status)
+
+	switch {
+	case !statusExpected(t.Status, resp.StatusCode):
+		res.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	case t.Body != "" && !strings.Contains(string(body), t.Body):
+		res.Error = "body does not contain expected text"
+	case t.BodyRe != nil && !t.BodyRe.Match(body):
+		res.Error = "body does not match body_regex"
+	case res.Blocked:
+		res.Error = "blocked: " + res.BlockReason
+	default:
+		res.Reachable = true
+	}
+	return res
+}
+
+const browserUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36"
+
+func statusExpected(want []int, got int) bool {
+	if len(want) == 0 {
+		return got/100 == 2
+	}
+	for _, code := range want {
+		if code == got {
+			return true
+		}
+	}
+	return false
+}
+
+// blockMarkers are body snippets of anti-bot interstitials, checked
+// case-insensitively. Plain captcha widgets and Cloudflare's
+// challenge-platform scripts are not on the list: plenty of real pages
+// embed them, so they only count on an error status.
+var blockMarkers = []struct {
+	marker, reason string
+}{
+	{"cf-chl-", "cloudflare challenge"},
+	{"attention required! | cloudflare", "cloudflare block"},
+	{"_incapsula_resource", "incapsula block"},
+	{"px-captcha", "perimeterx captcha"},
+	{"captcha-delivery.com", "datadome captcha"},
+	{"/sorry/index", "google unusual traffic"},
+	{"our systems have detected unusual traffic", "google unusual traffic"},
+	{"access denied</title>", "access denied page"},
+}
+
+// detectBlock recognizes block pages and challenges: explicit challenge
+// headers, known interstitial markers in the body and 403/429 statuses
+// (unless the target lists them as expected).
+func detectBlock(resp *http.Response, body []byte, expected []int) (bool, string) {
+	if resp.Header.Get("Cf-Mitigated") == "challenge" {
+		return true, "cloudflare challenge"
+	}
+
+	lower := strings.ToLower(string(body))
+	for _, m := range blockMarkers {
+		if strings.Contains(lower, m.marker) {
+			return true, m.reason
+		}
+	}
+
+	if len(expected) > 0 && statusExpected(expected, resp.StatusCode) {
+		return false, ""
+	}
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		// challenge pages are served with these; the JS detections
+		// script is in ordinary 200 pages too
+		if strings.Contains(lower, "/cdn-cgi/challenge-platform/") {
+			return true, "cloudflare challenge"
+		}
+	}
+	if resp.StatusCode >= 400 && strings.Contains(lower, "captcha") {
+		return true, fmt.Sprintf("captcha page (http %d)", resp.StatusCode)
+	}
+	switch resp.StatusCode {
+	case http.StatusForbidden:
+		return true, "http 403"
+	case http.StatusTooManyRequests:
+		return true, "http 429 rate limited"
+	}
+	return false, ""
+}
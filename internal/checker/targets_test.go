@@ -0,0 +1,54 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+func TestCheckTarget(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Write([]byte(`<html>{"ok": true} Add to cart</html>`))
+		case "/challenge":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`<script src="/cdn-cgi/challenge-platform/h/b/orchestrate"></script>`))
+		case "/jsd":
+			w.Write([]byte(`<html>Add to cart<script src="/cdn-cgi/challenge-platform/scripts/jsd/main.js"></script></html>`))
+		case "/captcha":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`<div class="g-recaptcha"></div>`))
+		case "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		name          string
+		target        model.Target
+		wantReachable bool
+		wantBlocked   string
+	}{
+		{"ok", model.Target{URL: srv.URL + "/ok", Body: "Add to cart"}, true, ""},
+		{"regex", model.Target{URL: srv.URL + "/ok", BodyRe: regexp.MustCompile(`"ok":\s*true`)}, true, ""},
+		{"body mismatch", model.Target{URL: srv.URL + "/ok", Body: "Sold out"}, false, ""},
+		{"cloudflare", model.Target{URL: srv.URL + "/challenge"}, false, "cloudflare challenge"},
+		{"cloudflare jsd on a 200 page", model.Target{URL: srv.URL + "/jsd", Body: "Add to cart"}, true, ""},
+		{"captcha", model.Target{URL: srv.URL + "/captcha"}, false, "captcha page (http 503)"},
+		{"403", model.Target{URL: srv.URL + "/forbidden"}, false, "http 403"},
+		{"403 expected", model.Target{URL: srv.URL + "/forbidden", Status: []int{403}}, true, ""},
+	}
+	for _, c := range cases {
+		res := checkTarget(context.Background(), srv.Client(), c.target)
+		if res.Reachable != c.wantReachable || res.BlockReason != c.wantBlocked {
+			t.Errorf("%s: got reachable=%v block=%q, want reachable=%v block=%q (err: %s)",
+				c.name, res.Reachable, res.BlockReason, c.wantReachable, c.wantBlocked, res.Error)
+		}
+	}
+}
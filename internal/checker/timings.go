@@ -0,0 +1,107 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+// phaseClock collects timestamps along one judge request. httptrace only
+// sees the transport's side, so the proxy dialers mark the steps inside
+// their DialContext (TCP to the proxy, TLS to the proxy, SOCKS/CONNECT
+// handshake) on the clock they find in the request context.
+type phaseClock struct {
+	mu sync.Mutex
+
+	dialStart      time.Time
+	proxyConnected time.Time
+	proxyTLSDone   time.Time
+	tunnelUp       time.Time
+	tlsStart       time.Time
+	tlsDone        time.Time
+	wroteRequest   time.Time
+	firstByte      time.Time
+}
+
+type phaseClockKey struct{}
+
+// withPhaseClock returns a ctx that carries c to the dialers and traces
+// the transport's TLS handshake, request write and first response byte.
+func withPhaseClock(ctx context.Context, c *phaseClock) context.Context {
+	ctx = context.WithValue(ctx, phaseClockKey{}, c)
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		TLSHandshakeStart:    func() { c.mark(&c.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { c.mark(&c.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { c.mark(&c.wroteRequest) },
+		GotFirstResponseByte: func() { c.mark(&c.firstByte) },
+	})
+}
+
+// dialPhase is a step inside a proxy dialer.
+type dialPhase int
+
+const (
+	phaseDialStart      dialPhase = iota // about to connect to the proxy
+	phaseProxyConnected                  // TCP connection to the proxy is up
+	phaseProxyTLSDone                    // TLS handshake with the proxy finished
+	phaseTunnelUp                        // SOCKS/CONNECT handshake finished
+)
+
+// markPhase records a dialer step on the clock in ctx, if there is one.
+func markPhase(ctx context.Context, p dialPhase) {
+	c, ok := ctx.Value(phaseClockKey{}).(*phaseClock)
+	if !ok {
+		return
+	}
+	switch p {
+	case phaseDialStart:
+		c.mark(&c.dialStart)
+	case phaseProxyConnected:
+		c.mark(&c.proxyConnected)
+	case phaseProxyTLSDone:
+		c.mark(&c.proxyTLSDone)
+	case phaseTunnelUp:
+		c.mark(&c.tunnelUp)
+	}
+}
+
+// mark sets *t to now unless it is already set: only the first
+// connection of a request counts.
+func (c *phaseClock) mark(t *time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.IsZero() {
+		*t = time.Now()
+	}
+}
+
+// timings turns the marks into phase durations; phases that did not
+// happen (cleartext proxy, reused connection, GET forwarding) stay 0.
+func (c *phaseClock) timings() model.Timings {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var t model.Timings
+	t.ProxyConnectMs = phaseMs(c.dialStart, c.proxyConnected)
+	t.ProxyTLSMs = phaseMs(c.proxyConnected, c.proxyTLSDone)
+
+	tunnelFrom := c.proxyConnected
+	if !c.proxyTLSDone.IsZero() {
+		tunnelFrom = c.proxyTLSDone
+	}
+	t.TunnelMs = phaseMs(tunnelFrom, c.tunnelUp)
+	t.TargetTLSMs = phaseMs(c.tlsStart, c.tlsDone)
+	t.FirstByteMs = phaseMs(c.wroteRequest, c.firstByte)
+	return t
+}
+
+func phaseMs(from, to time.Time) float64 {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return 0
+	}
+	return float64(to.Sub(from).Microseconds()) / 1000.0
+}
@@ -0,0 +1,115 @@
+package checker
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/August26/proxycheck-go/internal/model"
+)
+
+const pinPrefix = "sha256/"
+
+// ParseTLSPins parses a comma-separated --tls-pin value: public key pins
+// in the "sha256/<base64 of the SPKI hash>" form curl and HPKP use.
+func ParseTLSPins(s string) ([]string, error) {
+	var pins []string
+	for _, pin := range strings.Split(s, ",") {
+		pin = strings.TrimSpace(pin)
+		if pin == "" {
+			continue
+		}
+		if err := validatePin(pin); err != nil {
+			return nil, err
+		}
+		pins = append(pins, pin)
+	}
+	return pins, nil
+}
+
+func validatePin(pin string) error {
+	raw, ok := strings.CutPrefix(pin, pinPrefix)
+	if !ok {
+		return fmt.Errorf("tls pin %q: want sha256/<base64>", pin)
+	}
+	if sum, err := base64.StdEncoding.DecodeString(raw); err != nil || len(sum) != sha256.Size {
+		return fmt.Errorf("tls pin %q: not a base64 SHA-256", pin)
+	}
+	return nil
+}
+
+// spkiPin returns the public key pin of c.
+func spkiPin(c *x509.Certificate) string {
+	sum := sha256.Sum256(c.RawSubjectPublicKeyInfo)
+	return pinPrefix + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// tlsObservation is the certificate chain an HTTPS request through the
+// proxy actually received, whether or not the handshake went through.
+type tlsObservation struct {
+	chain     []*x509.Certificate // as sent by the peer, leaf first
+	verifyErr error               // why verification failed, nil if it passed
+}
+
+// observeTLS extracts the received chain from a response, or from the
+// verification error that aborted the request. The transport keeps
+// verifying as usual; the chain is only there to explain the outcome.
+func observeTLS(resp *http.Response, err error) tlsObservation {
+	if resp != nil && resp.TLS != nil {
+		return tlsObservation{chain: resp.TLS.PeerCertificates}
+	}
+	var verifyErr *tls.CertificateVerificationError
+	if errors.As(err, &verifyErr) {
+		return tlsObservation{chain: verifyErr.UnverifiedCertificates, verifyErr: verifyErr.Err}
+	}
+	return tlsObservation{}
+}
+
+// intercepted reports whether the chain was minted by someone other than
+// the site's CA. With pins the chain must contain a pinned key, leaf or
+// issuer, which also catches interception CAs that are trusted locally.
+// Without pins it means the leaf chains to an unknown authority; an
+// expired or misnamed certificate fails too, but isn't interception.
+func (o tlsObservation) intercepted(pins []string) bool {
+	if len(o.chain) == 0 {
+		return false
+	}
+	if len(pins) > 0 {
+		for _, c := range o.chain {
+			if slices.Contains(pins, spkiPin(c)) {
+				return false
+			}
+		}
+		return true
+	}
+	var unknownCA x509.UnknownAuthorityError
+	return errors.As(o.verifyErr, &unknownCA)
+}
+
+// issuer is the leaf's issuer, "" without a chain.
+func (o tlsObservation) issuer() string {
+	if len(o.chain) == 0 {
+		return ""
+	}
+	return o.chain[0].Issuer.String()
+}
+
+// applyJudgeTLS records what the judge's HTTPS chain looked like on out.
+// Plain-HTTP judge requests observe nothing and leave out alone.
+func applyJudgeTLS(out *model.ProxyCheckResult, o tlsObservation, cfg model.Config) {
+	if len(o.chain) == 0 {
+		return
+	}
+	out.TLSIntercepted = o.intercepted(cfg.JudgeTLSPins)
+	out.ObservedIssuer = o.issuer()
+	out.TLSChain = make([]model.CertInfo, 0, len(o.chain))
+	for _, c := range o.chain {
+		out.TLSChain = append(out.TLSChain, *certInfo(c))
+	}
+}
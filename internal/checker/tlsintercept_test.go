@@ -0,0 +1,62 @@
+package checker
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTLSInterception(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0) // the untrusted handshake fails on purpose
+	srv.StartTLS()
+	defer srv.Close()
+	leafPin := spkiPin(srv.Certificate())
+	otherPin := "sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+
+	fetch := func(client *http.Client) tlsObservation {
+		resp, err := client.Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return observeTLS(resp, err)
+	}
+	trusted := fetch(srv.Client())
+	untrusted := fetch(&http.Client{})
+
+	cases := []struct {
+		name string
+		obs  tlsObservation
+		pins []string
+		want bool
+	}{
+		{"trusted, no pins", trusted, nil, false},
+		{"unknown authority", untrusted, nil, true},
+		{"trusted, pin matches", trusted, []string{leafPin}, false},
+		{"trusted, pin mismatch", trusted, []string{otherPin}, true},
+		{"unknown authority, pin matches", untrusted, []string{otherPin, leafPin}, false},
+		{"nothing observed", tlsObservation{}, []string{otherPin}, false},
+	}
+	for _, c := range cases {
+		if got := c.obs.intercepted(c.pins); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+	if len(untrusted.chain) == 0 {
+		t.Error("chain not recovered from the verification error")
+	}
+}
+
+func TestParseTLSPins(t *testing.T) {
+	pins, err := ParseTLSPins(" sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=, ")
+	if err != nil || len(pins) != 1 {
+		t.Errorf("got %q, %v", pins, err)
+	}
+	for _, bad := range []string{"47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=", "sha256/abcd", "sha1/AAAA"} {
+		if _, err := ParseTLSPins(bad); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+}
@@ -1,142 +1,184 @@
 package checker
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"net"
+	"strings"
 	"time"
 )
 
-// supportsSocks5UDP attempts a minimal SOCKS5 handshake including UDP ASSOCIATE.
-// If it succeeds through the basic command flow, we consider UDP "supported".
-//
-// NOTE: This is a light probe. We are not yet doing a full UDP round-trip
-// (DNS query etc.). That can come later.
-func supportsSocks5UDP(ctx context.Context, host string, port int, username, password string) bool {
-	addr := fmt.Sprintf("%s:%d", host, port)
+// defaultUDPResolver answers the DNS query we relay through UDP ASSOCIATE.
+const defaultUDPResolver = "8.8.8.8:53"
 
-	dialer := net.Dialer{
-		Timeout: 3 * time.Second,
-	}
-	conn, err := dialer.DialContext(ctx, "tcp", addr)
-	if err != nil {
-		return false
-	}
-	defer conn.Close()
+// udpProbeName is the name we ask the resolver for; any answer will do.
+const udpProbeName = "example.com"
 
-	// 1. greeting
-	// build methods:
-	//   0x00 = no auth
-	//   0x02 = username/password
-	methods := []byte{0x00}
-	useAuth := false
-	if username != "" || password != "" {
-		methods = append(methods, 0x02)
-		useAuth = true
-	}
+// udpReplyTimeout caps how long we wait for the relayed DNS answer.
+const udpReplyTimeout = 3 * time.Second
 
-	req := []byte{0x05, byte(len(methods))}
-	req = append(req, methods...)
-	if _, err := conn.Write(req); err != nil {
-		return false
-	}
+var (
+	errUDPRelayUnroutable  = errors.New("relay_unroutable: proxy answered with a private BND.ADDR")
+	errUDPRelayUnreachable = errors.New("relay_unreachable: no answer through the UDP relay")
+)
 
-	// server chooses method
-	buf := make([]byte, 2)
-	if _, err := conn.Read(buf); err != nil {
-		return false
-	}
-	if len(buf) < 2 || buf[0] != 0x05 {
-		return false
-	}
-	chosenMethod := buf[1]
+// udpProbeResult is the outcome of a UDP round-trip through SOCKS5.
+type udpProbeResult struct {
+	OK        bool
+	LatencyMs int64
+	Err       string // failure reason when !OK
+}
 
-	// 2. auth if required
-	if useAuth && chosenMethod == 0x02 {
-		if err := socks5UserPassAuth(conn, username, password); err != nil {
-			return false
-		}
-	} else if chosenMethod == 0x00 {
-		// no auth
-	} else {
-		// proxy requested something we don't support
-		return false
+// supportsSocks5UDP does a real UDP round-trip through SOCKS5 UDP ASSOCIATE:
+// it encapsulates a DNS query for udpProbeName (RFC 1928 section 7), sends it
+// to the BND.ADDR/BND.PORT relay addressed to resolver, and waits for the
+// answer. Accepting ASSOCIATE alone proves nothing: many proxies do that and
+// then never relay a single datagram.
+func supportsSocks5UDP(ctx context.Context, d *socks5Dialer, resolver string) udpProbeResult {
+	if resolver == "" {
+		resolver = defaultUDPResolver
+	}
+	dst, err := net.ResolveUDPAddr("udp", resolver)
+	if err != nil {
+		return udpProbeResult{Err: "bad_resolver: " + err.Error()}
 	}
 
-	// 3. send UDP ASSOCIATE command
-	// VER=0x05 CMD=0x03 RSV=0x00 ATYP=0x01(IPv4) ADDR=0.0.0.0 PORT=0
-	// We basically ask it to open a UDP relay.
-	cmd := []byte{
-		0x05,       // VER
-		0x03,       // CMD = UDP ASSOCIATE
-		0x00,       // RSV
-		0x01,       // ATYP = IPv4 (we'll just say 0.0.0.0:0)
-		0x00, 0x00, 0x00, 0x00, // ADDR = 0.0.0.0
-		0x00, 0x00, // PORT = 0
+	ctrl, relay, err := d.associateUDP(ctx)
+	if err != nil {
+		return udpProbeResult{Err: "associate_failed: " + err.Error()}
 	}
+	// The relay only lives as long as the control connection.
+	defer ctrl.Close()
 
-	if _, err := conn.Write(cmd); err != nil {
-		return false
+	relayAddr, err := udpRelayAddr(relay, d.proxy.addr)
+	if err != nil {
+		return udpProbeResult{Err: err.Error()}
 	}
 
-	// 4. read reply
-	// Expected reply format:
-	// VER=0x05 REP=0x00 RSV=0x00 ATYP=... BND.ADDR ... BND.PORT ...
-	reply := make([]byte, 10)
-	n, err := conn.Read(reply)
+	var nd net.Dialer
+	conn, err := nd.DialContext(ctx, "udp", relayAddr)
 	if err != nil {
-		return false
-	}
-	if n < 4 {
-		return false
+		return udpProbeResult{Err: errUDPRelayUnreachable.Error() + ": " + err.Error()}
 	}
-	if reply[0] != 0x05 {
-		return false
+	defer conn.Close()
+
+	deadline := time.Now().Add(udpReplyTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
 	}
-	rep := reply[1]
-	if rep != 0x00 {
-		// non-zero REP means failure
-		return false
+	conn.SetDeadline(deadline)
+
+	query, id := buildDNSQuery(udpProbeName)
+	start := time.Now()
+	if _, err := conn.Write(socks5UDPPacket(dst, query)); err != nil {
+		return udpProbeResult{Err: errUDPRelayUnreachable.Error() + ": " + err.Error()}
 	}
 
-	// If we got here, proxy accepted UDP ASSOCIATE.
-	return true
+	buf := make([]byte, 2048)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return udpProbeResult{Err: errUDPRelayUnreachable.Error()}
+		}
+		payload, err := parseSOCKS5UDPPacket(buf[:n])
+		if err != nil {
+			continue
+		}
+		if isDNSAnswer(payload, id) {
+			return udpProbeResult{OK: true, LatencyMs: time.Since(start).Milliseconds()}
+		}
+	}
 }
 
-func socks5UserPassAuth(conn net.Conn, username, password string) error {
-	// Username/Password auth subnegotiation per RFC1929.
-	// auth packet:
-	// VER=0x01, ULEN, U, PLEN, P
-	ulen := len(username)
-	plen := len(password)
-	if ulen > 255 || plen > 255 {
-		return errors.New("username/password too long for socks5 auth")
+// udpRelayAddr turns the BND.ADDR/BND.PORT of the ASSOCIATE reply into the
+// address we send datagrams to. An unspecified BND.ADDR means "same host as
+// the proxy". A private BND.ADDR is useless from outside the proxy's network,
+// unless the proxy itself is on a private address.
+func udpRelayAddr(relay, proxyAddr string) (string, error) {
+	host, port, err := net.SplitHostPort(relay)
+	if err != nil {
+		return "", err
 	}
-	req := []byte{
-		0x01,
-		byte(ulen),
+	proxyHost, _, _ := net.SplitHostPort(proxyAddr)
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsUnspecified() {
+		return net.JoinHostPort(proxyHost, port), nil
 	}
-	req = append(req, []byte(username)...)
-	req = append(req, byte(plen))
-	req = append(req, []byte(password)...)
 
-	if _, err := conn.Write(req); err != nil {
-		return err
+	if isPrivateIP(ip) {
+		proxyIP := net.ParseIP(proxyHost)
+		if proxyIP == nil || !isPrivateIP(proxyIP) {
+			return "", fmt.Errorf("%w (%s)", errUDPRelayUnroutable, host)
+		}
 	}
+	return relay, nil
+}
 
-	resp := make([]byte, 2)
-	if _, err := conn.Read(resp); err != nil {
-		return err
+// socks5UDPPacket wraps payload in the RFC 1928 UDP request header:
+// RSV(2) FRAG(1) ATYP DST.ADDR DST.PORT DATA
+func socks5UDPPacket(dst *net.UDPAddr, payload []byte) []byte {
+	pkt := []byte{0x00, 0x00, 0x00}
+	if ip4 := dst.IP.To4(); ip4 != nil {
+		pkt = append(pkt, socks5AtypIPv4)
+		pkt = append(pkt, ip4...)
+	} else {
+		pkt = append(pkt, socks5AtypIPv6)
+		pkt = append(pkt, dst.IP.To16()...)
 	}
-	if len(resp) < 2 {
-		return errors.New("short auth response")
+	pkt = binary.BigEndian.AppendUint16(pkt, uint16(dst.Port))
+	return append(pkt, payload...)
+}
+
+// parseSOCKS5UDPPacket strips the UDP request header and returns DATA.
+// Fragmented datagrams (FRAG != 0) are not supported.
+func parseSOCKS5UDPPacket(pkt []byte) ([]byte, error) {
+	if len(pkt) < 4 {
+		return nil, errors.New("short udp packet")
 	}
-	if resp[0] != 0x01 {
-		return errors.New("invalid auth response version")
+	if pkt[2] != 0x00 {
+		return nil, errors.New("fragmented udp packet")
 	}
-	if resp[1] != 0x00 {
-		return errors.New("socks5 auth failed")
+	r := bytes.NewReader(pkt[4:])
+	if _, err := readSOCKS5Addr(r, pkt[3]); err != nil {
+		return nil, err
 	}
-	return nil
+	return pkt[len(pkt)-r.Len():], nil
+}
+
+// buildDNSQuery returns a recursive A query for name and its transaction ID.
+func buildDNSQuery(name string) ([]byte, uint16) {
+	var idb [2]byte
+	rand.Read(idb[:])
+	id := binary.BigEndian.Uint16(idb[:])
+
+	q := binary.BigEndian.AppendUint16(nil, id)
+	q = append(q,
+		0x01, 0x00, // flags: RD
+		0x00, 0x01, // QDCOUNT
+		0x00, 0x00, // ANCOUNT
+		0x00, 0x00, // NSCOUNT
+		0x00, 0x00, // ARCOUNT
+	)
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		q = append(q, byte(len(label)))
+		q = append(q, label...)
+	}
+	q = append(q, 0x00)
+	q = append(q,
+		0x00, 0x01, // QTYPE A
+		0x00, 0x01, // QCLASS IN
+	)
+	return q, id
+}
+
+// isDNSAnswer reports whether msg is a DNS response to transaction id.
+func isDNSAnswer(msg []byte, id uint16) bool {
+	return len(msg) >= 12 &&
+		binary.BigEndian.Uint16(msg[:2]) == id &&
+		msg[2]&0x80 != 0 // QR
 }
@@ -0,0 +1,127 @@
+package checker
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestSOCKS5UDPPacket(t *testing.T) {
+	payload := []byte("dns query")
+
+	for _, dst := range []*net.UDPAddr{
+		{IP: net.IPv4(8, 8, 8, 8), Port: 53},
+		{IP: net.ParseIP("2001:4860:4860::8888"), Port: 53},
+	} {
+		pkt := socks5UDPPacket(dst, payload)
+		wantAtyp := byte(socks5AtypIPv4)
+		if dst.IP.To4() == nil {
+			wantAtyp = socks5AtypIPv6
+		}
+		if !bytes.Equal(pkt[:3], []byte{0, 0, 0}) || pkt[3] != wantAtyp {
+			t.Errorf("%s: header % x", dst, pkt[:4])
+		}
+		if port := binary.BigEndian.Uint16(pkt[len(pkt)-len(payload)-2:]); port != 53 {
+			t.Errorf("%s: port %d", dst, port)
+		}
+		got, err := parseSOCKS5UDPPacket(pkt)
+		if err != nil || !bytes.Equal(got, payload) {
+			t.Errorf("%s: round trip got %q, %v", dst, got, err)
+		}
+	}
+
+	// relays may answer with a domain DST.ADDR
+	domain := []byte{0, 0, 0, socks5AtypDomain, 11}
+	domain = append(domain, "example.com"...)
+	domain = append(domain, 0, 53)
+	domain = append(domain, payload...)
+	if got, err := parseSOCKS5UDPPacket(domain); err != nil || !bytes.Equal(got, payload) {
+		t.Errorf("domain: got %q, %v", got, err)
+	}
+}
+
+func TestParseSOCKS5UDPPacketInvalid(t *testing.T) {
+	valid := socks5UDPPacket(&net.UDPAddr{IP: net.IPv4(8, 8, 8, 8), Port: 53}, []byte("x"))
+	fragmented := append([]byte{}, valid...)
+	fragmented[2] = 0x01
+
+	cases := []struct {
+		name string
+		pkt  []byte
+	}{
+		{"empty", nil},
+		{"short header", []byte{0, 0, 0}},
+		{"fragmented", fragmented},
+		{"truncated ipv4", valid[:7]},
+		{"truncated ipv6", []byte{0, 0, 0, socks5AtypIPv6, 0x20, 0x01}},
+		{"truncated domain", []byte{0, 0, 0, socks5AtypDomain, 11, 'e', 'x'}},
+		{"unknown atyp", []byte{0, 0, 0, 0x05, 1, 2, 3, 4, 0, 53}},
+	}
+	for _, c := range cases {
+		if _, err := parseSOCKS5UDPPacket(c.pkt); err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+	}
+}
+
+func TestUDPRelayAddr(t *testing.T) {
+	cases := []struct {
+		relay, proxy string
+		want         string
+		wantErr      error
+	}{
+		{"0.0.0.0:40000", "203.0.113.5:1080", "203.0.113.5:40000", nil},
+		{"[::]:40000", "203.0.113.5:1080", "203.0.113.5:40000", nil},
+		{"0.0.0.0:40000", "[2001:db8::5]:1080", "[2001:db8::5]:40000", nil},
+		{"198.51.100.7:40000", "203.0.113.5:1080", "198.51.100.7:40000", nil},
+		{"10.0.0.7:40000", "203.0.113.5:1080", "", errUDPRelayUnroutable},
+		{"10.0.0.7:40000", "10.0.0.5:1080", "10.0.0.7:40000", nil},
+		{"no-port", "203.0.113.5:1080", "", nil},
+	}
+	for _, c := range cases {
+		got, err := udpRelayAddr(c.relay, c.proxy)
+		switch {
+		case c.want == "" && err == nil:
+			t.Errorf("%s via %s: expected an error, got %q", c.relay, c.proxy, got)
+		case c.wantErr != nil && !errors.Is(err, c.wantErr):
+			t.Errorf("%s via %s: got error %v, want %v", c.relay, c.proxy, err, c.wantErr)
+		case c.want != "" && (got != c.want || err != nil):
+			t.Errorf("%s via %s: got %q, %v, want %q", c.relay, c.proxy, got, err, c.want)
+		}
+	}
+}
+
+func TestBuildDNSQuery(t *testing.T) {
+	q, id := buildDNSQuery("example.com.")
+	if binary.BigEndian.Uint16(q[:2]) != id {
+		t.Errorf("id in the query does not match %04x", id)
+	}
+	if q[2] != 0x01 || binary.BigEndian.Uint16(q[4:6]) != 1 {
+		t.Errorf("header % x: want RD and one question", q[:12])
+	}
+	if !strings.Contains(string(q[12:]), "\x07example\x03com\x00") {
+		t.Errorf("question % x", q[12:])
+	}
+
+	resp := append([]byte{}, q...)
+	resp[2] |= 0x80
+	cases := []struct {
+		name string
+		msg  []byte
+		id   uint16
+		want bool
+	}{
+		{"answer", resp, id, true},
+		{"other id", resp, id + 1, false},
+		{"query echoed back", q, id, false},
+		{"short", resp[:11], id, false},
+	}
+	for _, c := range cases {
+		if got := isDNSAnswer(c.msg, c.id); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
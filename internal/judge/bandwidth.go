@@ -0,0 +1,77 @@
+package judge
+
+import (
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBytes caps /bytes/N so a public judge can't be used to pull
+// unbounded traffic.
+const maxBytes = 64 << 20
+
+// payloadChunk is the random block /bytes/N repeats. Random rather than
+// zeros, so a compressing proxy can't make the link look faster.
+var payloadChunk = func() []byte {
+	b := make([]byte, 32<<10)
+	for i := range b {
+		b[i] = byte(rand.IntN(256))
+	}
+	return b
+}()
+
+// PostResponse is the JSON body of /post. Unlike httpbin it does not
+// echo the payload back, only its size.
+type PostResponse struct {
+	Origin  string            `json:"origin"`
+	Headers map[string]string `json:"headers"`
+	Bytes   int64             `json:"bytes"`   // request body size received
+	BodyMs  float64           `json:"body_ms"` // time spent reading the body
+}
+
+// handleBytes serves /bytes/N: N bytes of random data (httpbin-compatible).
+func handleBytes(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/bytes/"))
+	if err != nil || n < 0 {
+		http.Error(w, "usage: /bytes/<n>", http.StatusBadRequest)
+		return
+	}
+	if n > maxBytes {
+		n = maxBytes
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(n))
+	for n > 0 {
+		chunk := payloadChunk
+		if n < len(chunk) {
+			chunk = chunk[:n]
+		}
+		if _, err := w.Write(chunk); err != nil {
+			return
+		}
+		n -= len(chunk)
+	}
+}
+
+// handlePost serves /post: it drains the request body and reports how
+// much arrived, for upload measurements.
+func handlePost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		http.Error(w, "use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	start := time.Now()
+	n, _ := io.Copy(io.Discard, io.LimitReader(r.Body, maxBytes))
+
+	writeJSON(w, PostResponse{
+		Origin:  originIP(r),
+		Headers: flattenHeaders(r),
+		Bytes:   n,
+		BodyMs:  msSince(start),
+	})
+}
@@ -0,0 +1,139 @@
+package judge
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+)
+
+// maxHeaderBlock bounds how much of a request we keep for header_order.
+const maxHeaderBlock = 64 << 10
+
+// Listener wraps a TCP listener so every accepted connection records
+// its raw request header block, accept time and (for TLS) ClientHello.
+type Listener struct {
+	net.Listener
+	tlsConfig *tls.Config // nil for plain HTTP
+}
+
+// NewListener returns a recording listener around ln. When tlsConfig is
+// non-nil connections are served over TLS.
+func NewListener(ln net.Listener, tlsConfig *tls.Config) *Listener {
+	return &Listener{Listener: ln, tlsConfig: tlsConfig}
+}
+
+// Accept waits for the next connection and wraps it for recording.
+func (l *Listener) Accept() (net.Conn, error) {
+	raw, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	rc := &recordingConn{accepted: time.Now()}
+	if l.tlsConfig == nil {
+		rc.Conn = raw
+		return rc, nil
+	}
+
+	conf := l.tlsConfig.Clone()
+	conf.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		rc.mu.Lock()
+		rc.hello = fingerprint(hello)
+		rc.mu.Unlock()
+		return nil, nil
+	}
+	tc := tls.Server(raw, conf)
+	rc.Conn = tc
+	rc.tls = tc
+	return rc, nil
+}
+
+// recordingConn captures the plaintext bytes of the request header block.
+type recordingConn struct {
+	net.Conn
+	tls      *tls.Conn
+	accepted time.Time
+
+	mu          sync.Mutex
+	buf         bytes.Buffer
+	headersDone time.Time
+	handshakeMs float64
+	hello       clientHello
+}
+
+func (c *recordingConn) Read(p []byte) (int, error) {
+	if c.tls != nil && !c.tls.ConnectionState().HandshakeComplete {
+		start := time.Now()
+		if err := c.tls.Handshake(); err != nil {
+			return 0, err
+		}
+		c.mu.Lock()
+		c.handshakeMs = msSince(start)
+		c.mu.Unlock()
+	}
+
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.record(p[:n])
+	}
+	return n, err
+}
+
+func (c *recordingConn) record(p []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.headersDone.IsZero() || c.buf.Len() >= maxHeaderBlock {
+		return
+	}
+	c.buf.Write(p)
+	if i := bytes.Index(c.buf.Bytes(), []byte("\r\n\r\n")); i >= 0 {
+		c.buf.Truncate(i)
+		c.headersDone = time.Now()
+	}
+}
+
+// headerBlock returns the request line and headers, without the final CRLFCRLF.
+func (c *recordingConn) headerBlock() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]byte(nil), c.buf.Bytes()...)
+}
+
+func (c *recordingConn) tlsInfo() *TLSInfo {
+	if c.tls == nil {
+		return nil
+	}
+	st := c.tls.ConnectionState()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &TLSInfo{
+		Version:     tls.VersionName(st.Version),
+		CipherSuite: tls.CipherSuiteName(st.CipherSuite),
+		ServerName:  st.ServerName,
+		ALPN:        st.NegotiatedProtocol,
+		JA3:         c.hello.ja3,
+		JA3Hash:     c.hello.ja3Hash,
+	}
+}
+
+func (c *recordingConn) timing() Timing {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := Timing{
+		AcceptedAt:     c.accepted,
+		TLSHandshakeMs: c.handshakeMs,
+	}
+	if !c.headersDone.IsZero() {
+		t.HeadersMs = float64(c.headersDone.Sub(c.accepted).Microseconds()) / 1000.0
+	}
+	return t
+}
+
+func msSince(t time.Time) float64 {
+	return float64(time.Since(t).Microseconds()) / 1000.0
+}
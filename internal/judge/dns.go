@@ -0,0 +1,183 @@
+package judge
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dnsLogTTL is how long a query stays available from /dns/<name>.
+const dnsLogTTL = 10 * time.Minute
+
+// DNSQuery is one query the DNS stand-in received for a name.
+type DNSQuery struct {
+	Resolver string    `json:"resolver"` // source IP: the resolver that asked
+	Type     string    `json:"type"`     // "A", "AAAA", ...
+	At       time.Time `json:"at"`
+}
+
+// DNSResponse is the JSON body of /dns/<name>.
+type DNSResponse struct {
+	Name    string     `json:"name"`
+	Queries []DNSQuery `json:"queries"` // oldest first, empty if nobody asked
+}
+
+// DNSServer is an authoritative stand-in for a zone delegated to the
+// judge host. It answers every name in Zone, and /dns/<name> reports
+// which resolvers asked for it: resolve a name nobody has asked for
+// before through a proxy, and the resolvers that show up are the ones
+// the proxy uses.
+type DNSServer struct {
+	Zone   string // e.g. "leak.example.com"
+	Answer net.IP // returned for A/AAAA queries in Zone; nil answers without records
+}
+
+// Serve answers queries on pc until reading from it fails.
+func (s *DNSServer) Serve(pc net.PacketConn) error {
+	zone := strings.ToLower(strings.TrimSuffix(s.Zone, "."))
+	buf := make([]byte, 4096)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		if resp := s.answer(zone, buf[:n], addr); resp != nil {
+			pc.WriteTo(resp, addr)
+		}
+	}
+}
+
+var dnsTypes = map[uint16]string{
+	1: "A", 2: "NS", 5: "CNAME", 6: "SOA", 15: "MX", 16: "TXT",
+	28: "AAAA", 33: "SRV", 65: "HTTPS", 255: "ANY",
+}
+
+// answer builds the response to query, nil for packets that don't
+// deserve one. Names outside the zone are refused.
+func (s *DNSServer) answer(zone string, query []byte, from net.Addr) []byte {
+	if len(query) < 12 || query[2]&0x80 != 0 || binary.BigEndian.Uint16(query[4:6]) != 1 {
+		return nil
+	}
+
+	// question: labels up to the root, then QTYPE and QCLASS
+	var labels []string
+	off := 12
+	for {
+		if off >= len(query) {
+			return nil
+		}
+		n := int(query[off])
+		if n == 0 {
+			off++
+			break
+		}
+		// no compression pointers in a question, names up to 255 bytes
+		if n > 63 || off+1+n > len(query) || off+1+n-12 > 254 { // 255 with the root label
+			return nil
+		}
+		labels = append(labels, string(query[off+1:off+1+n]))
+		off += 1 + n
+	}
+	if off+4 > len(query) {
+		return nil
+	}
+	question := query[12 : off+4]
+	qtype := binary.BigEndian.Uint16(query[off : off+2])
+
+	// resolvers randomize the case of names they ask for (0x20)
+	name := strings.ToLower(strings.Join(labels, "."))
+	inZone := name == zone || strings.HasSuffix(name, "."+zone)
+
+	flags := uint16(0x8000) | binary.BigEndian.Uint16(query[2:4])&0x7900 // QR, opcode, RD
+	var answers []byte
+	if !inZone {
+		flags |= 5 // REFUSED
+	} else {
+		flags |= 0x0400 // AA
+		typ := dnsTypes[qtype]
+		if typ == "" {
+			typ = fmt.Sprintf("TYPE%d", qtype)
+		}
+		if host, _, err := net.SplitHostPort(from.String()); err == nil {
+			dnsLog.add(name, DNSQuery{Resolver: host, Type: typ, At: time.Now()})
+		}
+		answers = s.record(qtype)
+	}
+
+	resp := binary.BigEndian.AppendUint16(nil, binary.BigEndian.Uint16(query[0:2]))
+	resp = binary.BigEndian.AppendUint16(resp, flags)
+	resp = binary.BigEndian.AppendUint16(resp, 1) // QDCOUNT
+	ancount := uint16(0)
+	if answers != nil {
+		ancount = 1
+	}
+	resp = binary.BigEndian.AppendUint16(resp, ancount)
+	resp = append(resp, 0, 0, 0, 0) // NSCOUNT, ARCOUNT
+	resp = append(resp, question...)
+	return append(resp, answers...)
+}
+
+// record returns the answer record for qtype, pointing back at the
+// question's name, or nil if Answer has none of that type.
+func (s *DNSServer) record(qtype uint16) []byte {
+	var rdata []byte
+	switch {
+	case qtype == 1 && s.Answer.To4() != nil:
+		rdata = s.Answer.To4()
+	case qtype == 28 && s.Answer != nil && s.Answer.To4() == nil:
+		rdata = s.Answer.To16()
+	default:
+		return nil
+	}
+	rr := []byte{0xc0, 12} // name: pointer to the question
+	rr = binary.BigEndian.AppendUint16(rr, qtype)
+	rr = binary.BigEndian.AppendUint16(rr, 1) // class IN
+	rr = binary.BigEndian.AppendUint32(rr, 0) // TTL: every lookup should reach us
+	rr = binary.BigEndian.AppendUint16(rr, uint16(len(rdata)))
+	return append(rr, rdata...)
+}
+
+// dnsLog holds recent queries for every DNSServer in the process, so
+// /dns/<name> works on whichever listener it is asked.
+var dnsLog = &queryLog{byName: map[string][]DNSQuery{}}
+
+type queryLog struct {
+	mu        sync.Mutex
+	byName    map[string][]DNSQuery
+	lastSweep time.Time
+}
+
+func (l *queryLog) add(name string, q DNSQuery) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if q.At.Sub(l.lastSweep) > time.Minute {
+		for n, qs := range l.byName {
+			if q.At.Sub(qs[len(qs)-1].At) > dnsLogTTL {
+				delete(l.byName, n)
+			}
+		}
+		l.lastSweep = q.At
+	}
+	l.byName[name] = append(l.byName[name], q)
+}
+
+func (l *queryLog) get(name string) []DNSQuery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]DNSQuery{}, l.byName[name]...)
+}
+
+// handleDNS serves /dns/<name>: the queries the DNS stand-in received
+// for name.
+func handleDNS(w http.ResponseWriter, r *http.Request) {
+	name := strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/dns/"), "."))
+	if name == "" {
+		http.Error(w, "usage: /dns/<name>", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, DNSResponse{Name: name, Queries: dnsLog.get(name)})
+}
@@ -0,0 +1,92 @@
+package judge
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+)
+
+var testResolver = &net.UDPAddr{IP: net.IPv4(192, 0, 2, 53), Port: 5353}
+
+// dnsQuery builds a query for name with the given type; labels are
+// written as given, so their case survives.
+func dnsQuery(name string, qtype uint16) []byte {
+	q := []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+	start := 0
+	for i := 0; i <= len(name); i++ {
+		if i == len(name) || name[i] == '.' {
+			q = append(q, byte(i-start))
+			q = append(q, name[start:i]...)
+			start = i + 1
+		}
+	}
+	q = append(q, 0)
+	q = binary.BigEndian.AppendUint16(q, qtype)
+	return binary.BigEndian.AppendUint16(q, 1)
+}
+
+func TestDNSServerAnswer(t *testing.T) {
+	s := &DNSServer{Zone: "leak.test", Answer: net.IPv4(198, 51, 100, 1)}
+
+	cases := []struct {
+		name      string
+		query     []byte
+		wantRcode int // -1: no response
+		wantAns   int
+	}{
+		{"a in zone", dnsQuery("pc-1.leak.test", 1), 0, 1},
+		{"0x20 mixed case", dnsQuery("PC-2.LeAk.TeSt", 1), 0, 1},
+		{"aaaa without v6 answer", dnsQuery("pc-3.leak.test", 28), 0, 0},
+		{"outside the zone", dnsQuery("example.com", 1), 5, 0},
+		{"suffix is not the zone", dnsQuery("notleak.test", 1), 5, 0},
+		{"response packet", func() []byte { q := dnsQuery("pc-4.leak.test", 1); q[2] |= 0x80; return q }(), -1, 0},
+		{"two questions", func() []byte { q := dnsQuery("pc-5.leak.test", 1); q[5] = 2; return q }(), -1, 0},
+		{"compressed pointer", []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0, 0xc0, 0x0c, 0, 1, 0, 1}, -1, 0},
+		{"pointer loop after a label", []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0, 2, 'p', 'c', 0xc0, 0x0c, 0, 1, 0, 1}, -1, 0},
+		{"label past the end", []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0, 40, 'p', 'c'}, -1, 0},
+		{"name over 255 bytes", dnsQuery(strings.Repeat("a.", 130)+"leak.test", 1), -1, 0},
+		{"header only", []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}, -1, 0},
+		{"empty", nil, -1, 0},
+	}
+	for _, c := range cases {
+		resp := s.answer("leak.test", c.query, testResolver)
+		if c.wantRcode < 0 {
+			if resp != nil {
+				t.Errorf("%s: expected no response, got % x", c.name, resp)
+			}
+			continue
+		}
+		if len(resp) < 12 {
+			t.Fatalf("%s: short response % x", c.name, resp)
+		}
+		if id := binary.BigEndian.Uint16(resp[0:2]); id != 0x1234 {
+			t.Errorf("%s: id 0x%04x", c.name, id)
+		}
+		if rcode := int(resp[3] & 0x0f); rcode != c.wantRcode {
+			t.Errorf("%s: rcode %d, want %d", c.name, rcode, c.wantRcode)
+		}
+		if an := int(binary.BigEndian.Uint16(resp[6:8])); an != c.wantAns {
+			t.Errorf("%s: %d answers, want %d", c.name, an, c.wantAns)
+		}
+		if c.wantAns > 0 && !net.IP(resp[len(resp)-4:]).Equal(s.Answer) {
+			t.Errorf("%s: answer % x, want %s", c.name, resp[len(resp)-4:], s.Answer)
+		}
+	}
+
+	// queries are logged by lower-cased name, with the resolver's address
+	if got := dnsLog.get("pc-2.leak.test"); len(got) != 1 || got[0].Resolver != "192.0.2.53" || got[0].Type != "A" {
+		t.Errorf("logged queries for pc-2.leak.test: %+v", got)
+	}
+}
+
+func TestDNSServerAnswerTruncated(t *testing.T) {
+	s := &DNSServer{Zone: "leak.test", Answer: net.IPv4(198, 51, 100, 1)}
+	query := dnsQuery("pc-cut.leak.test", 1)
+	// every cut of a valid query must be refused without a panic
+	for n := 0; n < len(query); n++ {
+		if resp := s.answer("leak.test", query[:n], testResolver); resp != nil {
+			t.Errorf("%d of %d bytes: expected no response, got % x", n, len(query), resp)
+		}
+	}
+}
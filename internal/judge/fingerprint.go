@@ -0,0 +1,70 @@
+package judge
+
+import (
+	"crypto/md5"
+	"crypto/tls"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// clientHello holds the JA3 fingerprint of a TLS ClientHello.
+type clientHello struct {
+	ja3     string
+	ja3Hash string
+}
+
+// fingerprint computes JA3 (SSLVersion,Ciphers,Extensions,Curves,PointFormats)
+// from the parts of the ClientHello that crypto/tls exposes. GREASE values
+// are skipped as the JA3 spec requires.
+//
+// crypto/tls does not expose the legacy_version field, so it is derived
+// from supported_versions: TLS 1.3 clients send 0x0303 there anyway.
+func fingerprint(h *tls.ClientHelloInfo) clientHello {
+	version := uint16(0)
+	for _, v := range h.SupportedVersions {
+		if !isGREASE(v) && v > version {
+			version = v
+		}
+	}
+	if version > tls.VersionTLS12 {
+		version = tls.VersionTLS12
+	}
+
+	curves := make([]uint16, 0, len(h.SupportedCurves))
+	for _, c := range h.SupportedCurves {
+		curves = append(curves, uint16(c))
+	}
+	points := make([]uint16, 0, len(h.SupportedPoints))
+	for _, p := range h.SupportedPoints {
+		points = append(points, uint16(p))
+	}
+
+	ja3 := strings.Join([]string{
+		strconv.Itoa(int(version)),
+		joinUint16(h.CipherSuites),
+		joinUint16(h.Extensions),
+		joinUint16(curves),
+		joinUint16(points),
+	}, ",")
+
+	sum := md5.Sum([]byte(ja3))
+	return clientHello{ja3: ja3, ja3Hash: hex.EncodeToString(sum[:])}
+}
+
+func joinUint16(vals []uint16) string {
+	parts := make([]string, 0, len(vals))
+	for _, v := range vals {
+		if isGREASE(v) {
+			continue
+		}
+		parts = append(parts, strconv.Itoa(int(v)))
+	}
+	return strings.Join(parts, "-")
+}
+
+// isGREASE reports whether v is one of the RFC 8701 reserved values
+// (0x0a0a, 0x1a1a, ... 0xfafa).
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
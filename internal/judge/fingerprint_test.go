@@ -0,0 +1,65 @@
+package judge
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+func TestIsGREASE(t *testing.T) {
+	for _, v := range []uint16{0x0a0a, 0x1a1a, 0x6a6a, 0xfafa} {
+		if !isGREASE(v) {
+			t.Errorf("0x%04x: not recognized as GREASE", v)
+		}
+	}
+	for _, v := range []uint16{0x0000, 0x0a1a, 0x1a0a, 0x0a0b, 0x1301, 0x0303} {
+		if isGREASE(v) {
+			t.Errorf("0x%04x: taken for GREASE", v)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	cases := []struct {
+		name    string
+		hello   tls.ClientHelloInfo
+		wantJA3 string
+	}{
+		{
+			name: "grease everywhere is skipped",
+			hello: tls.ClientHelloInfo{
+				SupportedVersions: []uint16{0x2a2a, tls.VersionTLS13, tls.VersionTLS12},
+				CipherSuites:      []uint16{0x0a0a, 0x1301, 0x1302, 0xc02b},
+				Extensions:        []uint16{0x3a3a, 0, 23, 65281, 10, 11, 0x4a4a},
+				SupportedCurves:   []tls.CurveID{0x5a5a, tls.X25519, tls.CurveP256},
+				SupportedPoints:   []uint8{0},
+			},
+			// TLS 1.3 clients report 771 (TLS 1.2) as their version
+			wantJA3: "771,4865-4866-49195,0-23-65281-10-11,29-23,0",
+		},
+		{
+			name: "tls 1.2 client",
+			hello: tls.ClientHelloInfo{
+				SupportedVersions: []uint16{tls.VersionTLS12, tls.VersionTLS11},
+				CipherSuites:      []uint16{0xc02f, 0x009c},
+				Extensions:        []uint16{0, 10, 11},
+				SupportedCurves:   []tls.CurveID{tls.CurveP256},
+				SupportedPoints:   []uint8{0, 1},
+			},
+			wantJA3: "771,49199-156,0-10-11,23,0-1",
+		},
+		{
+			name:    "empty hello",
+			hello:   tls.ClientHelloInfo{},
+			wantJA3: "0,,,,",
+		},
+	}
+	for _, c := range cases {
+		got := fingerprint(&c.hello)
+		if got.ja3 != c.wantJA3 {
+			t.Errorf("%s: ja3 %q, want %q", c.name, got.ja3, c.wantJA3)
+		}
+		if len(got.ja3Hash) != 32 {
+			t.Errorf("%s: ja3 hash %q is not an md5", c.name, got.ja3Hash)
+		}
+	}
+}
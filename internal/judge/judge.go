@@ -0,0 +1,167 @@
+// Package judge implements a self-hostable echo endpoint ("proxy judge").
+//
+// Its /get response is a superset of httpbin's: besides "origin" and
+// "headers" it reports the headers in the order and casing they arrived,
+// the TLS ClientHello fingerprint and request timing, so checks can run
+// against it without sending traffic to a third-party service.
+//
+// /bytes/N and /post serve and sink payloads for bandwidth measurements,
+// and /payload serves a fixed page for content integrity checks.
+// /dns/<name> reports the resolvers that asked a DNSServer for name, for
+// DNS leak tests.
+package judge
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Header is a single request header exactly as it was received.
+type Header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// TLSInfo describes the client side of the TLS handshake.
+type TLSInfo struct {
+	Version     string `json:"version"`
+	CipherSuite string `json:"cipher_suite"`
+	ServerName  string `json:"server_name"`
+	ALPN        string `json:"alpn"`
+	JA3         string `json:"ja3"`      // JA3 fingerprint string of the ClientHello
+	JA3Hash     string `json:"ja3_hash"` // md5 of JA3
+}
+
+// Timing is measured on the judge side of the connection.
+type Timing struct {
+	AcceptedAt     time.Time `json:"accepted_at"`      // TCP accept
+	TLSHandshakeMs float64   `json:"tls_handshake_ms"` // 0 for plain HTTP
+	HeadersMs      float64   `json:"headers_ms"`       // accept -> end of request headers
+}
+
+// Response is the JSON body of /get.
+type Response struct {
+	Origin      string            `json:"origin"`  // peer IP of the connection
+	Headers     map[string]string `json:"headers"` // httpbin-compatible view
+	HeaderOrder []Header          `json:"header_order"`
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	TLS         *TLSInfo          `json:"tls,omitempty"`
+	Timing      Timing            `json:"timing"`
+}
+
+type connKey struct{}
+
+// NewHandler returns the judge's HTTP handler. It expects connections
+// to come from a Listener created by this package.
+func NewHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/get", handleGet)
+	mux.HandleFunc("/ip", handleIP)
+	mux.HandleFunc("/bytes/", handleBytes)
+	mux.HandleFunc("/post", handlePost)
+	mux.HandleFunc("/payload", handlePayload)
+	mux.HandleFunc("/dns/", handleDNS)
+	return mux
+}
+
+// NewServer wraps NewHandler in an *http.Server wired to read the
+// recorded connection state. Keep-alives are disabled so that every
+// connection carries exactly one request and its raw header block.
+func NewServer() *http.Server {
+	srv := &http.Server{
+		Handler:           NewHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(ctx, connKey{}, c)
+		},
+	}
+	srv.SetKeepAlivesEnabled(false)
+	return srv
+}
+
+func handleGet(w http.ResponseWriter, r *http.Request) {
+	resp := Response{
+		Origin:  originIP(r),
+		Headers: flattenHeaders(r),
+		Method:  r.Method,
+		URL:     requestURL(r),
+	}
+
+	if rc, ok := r.Context().Value(connKey{}).(*recordingConn); ok {
+		resp.HeaderOrder = parseHeaderBlock(rc.headerBlock())
+		resp.TLS = rc.tlsInfo()
+		resp.Timing = rc.timing()
+	}
+
+	writeJSON(w, resp)
+}
+
+func handleIP(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]string{"origin": originIP(r)})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func originIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// flattenHeaders mimics httpbin: canonical names, repeated values joined
+// with ",", and the Host header included.
+func flattenHeaders(r *http.Request) map[string]string {
+	out := make(map[string]string, len(r.Header)+1)
+	for k, v := range r.Header {
+		out[k] = strings.Join(v, ",")
+	}
+	out["Host"] = r.Host
+	return out
+}
+
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if rc, ok := r.Context().Value(connKey{}).(*recordingConn); ok && rc.tls != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// parseHeaderBlock splits a raw "request-line CRLF headers" block into
+// headers, keeping order and casing. Obsolete line folding is joined
+// onto the previous header.
+func parseHeaderBlock(block []byte) []Header {
+	lines := strings.Split(string(block), "\r\n")
+	if len(lines) < 2 {
+		return nil
+	}
+
+	var out []Header
+	for _, line := range lines[1:] {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(out) > 0 {
+			out[len(out)-1].Value += " " + strings.TrimSpace(line)
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		out = append(out, Header{Name: name, Value: strings.TrimSpace(value)})
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+package judge
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseHeaderBlock(t *testing.T) {
+	cases := []struct {
+		name  string
+		block string
+		want  []Header
+	}{
+		{
+			name:  "order and casing kept",
+			block: "GET /get HTTP/1.1\r\nhost: judge\r\nUSER-AGENT: curl/8.0\r\nx-Forwarded-For: 10.0.0.1\r\n",
+			want: []Header{
+				{"host", "judge"},
+				{"USER-AGENT", "curl/8.0"},
+				{"x-Forwarded-For", "10.0.0.1"},
+			},
+		},
+		{
+			name:  "repeated names stay separate",
+			block: "GET / HTTP/1.1\r\nVia: 1.1 a\r\nHost: judge\r\nvia: 1.1 b\r\nVia: 1.1 c\r\n",
+			want: []Header{
+				{"Via", "1.1 a"},
+				{"Host", "judge"},
+				{"via", "1.1 b"},
+				{"Via", "1.1 c"},
+			},
+		},
+		{
+			name:  "folded line joins the previous header",
+			block: "GET / HTTP/1.1\r\nX-Long: one\r\n\t two\r\n  three\r\nHost: judge\r\n",
+			want: []Header{
+				{"X-Long", "one two three"},
+				{"Host", "judge"},
+			},
+		},
+		{
+			name:  "value keeps inner colons, lines without one are dropped",
+			block: "GET / HTTP/1.1\r\nHost: [2001:db8::1]:8080\r\ngarbage\r\nX-A:  spaced  \r\n",
+			want: []Header{
+				{"Host", "[2001:db8::1]:8080"},
+				{"X-A", "spaced"},
+			},
+		},
+		{
+			name:  "request line only",
+			block: "GET / HTTP/1.1",
+			want:  nil,
+		},
+		{
+			name:  "empty",
+			block: "",
+			want:  nil,
+		},
+	}
+	for _, c := range cases {
+		if got := parseHeaderBlock([]byte(c.block)); !slices.Equal(got, c.want) {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
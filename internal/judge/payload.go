@@ -0,0 +1,61 @@
+package judge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+// Payload is the fixed page /payload serves for integrity checks. It has
+// what tampering proxies go after: scripts, links, a form and a closing
+// body tag to inject before.
+var Payload = []byte(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>proxycheck-go integrity payload</title>
+<link rel="stylesheet" href="https://cdn.example.com/style.css">
+<script src="https://cdn.example.com/app.js" integrity="sha384-oqVuAfXRKap7fdgcCY5uykM6+R9GqQ8K/uxy9rx7HNQlGYl1kPzQho1wx4JwY8wC" crossorigin="anonymous"></script>
+</head>
+<body>
+<h1>proxycheck-go integrity payload</h1>
+<p>This page is served byte for byte the same on every request. A proxy
+that changes it, or its security headers, is tampering with traffic.</p>
+<p><a href="https://example.com/download/setup.exe">Download</a>
+<a href="https://example.com/login">Log in</a></p>
+<form action="https://example.com/login" method="post">
+<input type="text" name="user"><input type="password" name="pass">
+<button type="submit">Sign in</button>
+</form>
+<img src="https://cdn.example.com/logo.png" alt="logo">
+<script>document.title = document.title;</script>
+</body>
+</html>
+`)
+
+// PayloadSHA256 is the hex SHA-256 of Payload.
+var PayloadSHA256 = func() string {
+	sum := sha256.Sum256(Payload)
+	return hex.EncodeToString(sum[:])
+}()
+
+// PayloadHeaders are the security headers /payload is served with.
+var PayloadHeaders = map[string]string{
+	"Content-Security-Policy":   "default-src 'self' https://cdn.example.com; form-action https://example.com",
+	"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+	"X-Frame-Options":           "DENY",
+	"X-Content-Type-Options":    "nosniff",
+	"Referrer-Policy":           "no-referrer",
+	"Permissions-Policy":        "camera=(), microphone=(), geolocation=()",
+}
+
+// handlePayload serves Payload. no-transform asks well-behaved caches
+// and compressors to leave it alone, so any change is deliberate.
+func handlePayload(w http.ResponseWriter, r *http.Request) {
+	for k, v := range PayloadHeaders {
+		w.Header().Set(k, v)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store, no-transform")
+	w.Write(Payload)
+}
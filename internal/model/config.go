@@ -1,9 +1,16 @@
 package model
 
+import (
+	"crypto/tls"
+	"regexp"
+	"time"
+)
+
 type GeoInfo struct {
     Country string
     City    string
     ISP     string
+    ASN     string // "AS15169", empty if unknown
 }
 
 type IPResolver interface {
@@ -11,7 +18,7 @@ type IPResolver interface {
 }
 
 type Config struct {
-    ProxyType       string // https or socks5
+    ProxyType       string // http, https, socks4, socks4a, socks5, socks5h or auto
     TimeoutSeconds  int
     InputFile       string
     OutputFile      string
@@ -21,5 +28,88 @@ type Config struct {
 	Verbose          bool
 	Retries           int // how many retry attempts per proxy
 	Resolver 		IPResolver
+
+	// TLS to the proxy endpoint itself (HTTPS proxies, SOCKS over TLS)
+	ProxyTLS           bool   // wrap every proxy connection in TLS
+	ProxyTLSServerName string // SNI / verification name override, default is the proxy host
+	ProxyTLSConfig     *tls.Config // CA bundle / insecure settings, built from flags
+
+	UDPResolver      string            // DNS server (host:port) queried through the SOCKS5 UDP relay
+	CapabilityProbes []CapabilityProbe // extra named port probes from --capabilities-file
+	Targets          []Target          // sites fetched through every alive proxy (--targets)
+	LeakRules        []LeakRule        // anonymity leak rules (--leak-rules); nil means the built-in set
+
+	// Latency sampling, off when Samples is 0
+	Samples     int  // successful judge requests to collect per proxy
+	SampleReuse bool // reuse one keep-alive tunnel for every sample instead of re-dialing
+
+	// Bandwidth test, off when BandwidthBytes is 0
+	BandwidthBytes       int    // payload size per direction
+	BandwidthDownloadURL string // default: /bytes/N on the judge host
+	BandwidthUploadURL   string // default: /post on the judge host
+
+	// Rotating / backconnect gateway profiling, off when RotationRequests < 2
+	RotationRequests int           // judge requests per pass, each on a fresh connection
+	RotationInterval time.Duration // pause between requests; spaces out the sticky-session pass
+	SessionFormat    string        // session username template, {user} and {session} are substituted
+
+	Fingerprint bool   // identify the proxy software (--fingerprint)
+	IPv6Check   bool   // probe whether alive proxies egress over IPv6 (--ipv6)
+	IPv6URL     string // IPv6-only endpoint for that probe; default: api6.ipify.org with the default judge, none otherwise
+
+	// DNS leak test, off when DNSLeakZone is empty
+	DNSLeakZone string // zone served by the judge's DNS stand-in; unique names under it are resolved through the proxy
+
+	// Content integrity check, off unless IntegrityCheck
+	IntegrityCheck  bool
+	IntegrityURL    string // payload fetched over http:// and https://; default: /payload on the judge host
+	IntegritySHA256 string // expected hex SHA-256 of the IntegrityURL body
+
+	// Judge endpoint (httpbin-compatible echo service)
+	JudgeURL       string      // https:// endpoint fetched through the proxy
+	JudgePlainURL  string      // http:// endpoint for GET-forwarding checks; derived from JudgeURL if empty
+	JudgeTLSConfig *tls.Config // trusts --judge-ca on top of system roots; nil for defaults
+	JudgeTLSPins   []string    // "sha256/<base64>" public key pins for the judge's chain (--tls-pin)
+	RealClientIP   string      // our own public IP (--real-ip, or a direct judge request); searched for in leaks
 }
 
+// CapabilityProbe is one named entry of the capability matrix: the proxy
+// passes it if any of Targets can be reached through the tunnel and, when
+// Expect is set, the first bytes the server sends match it.
+type CapabilityProbe struct {
+	Name    string   `json:"name"`
+	Targets []string `json:"targets"`          // host:port, tried in order
+	TLS     bool     `json:"tls,omitempty"`    // handshake TLS before reading the banner
+	Expect  string   `json:"expect,omitempty"` // banner regex; empty means connecting is enough
+
+	ExpectRe *regexp.Regexp `json:"-"` // compiled Expect, set by the loader
+}
+
+// Target is a site a proxy must be able to use, not just reach: the
+// answer must have an expected status, contain the expected body and
+// not be a block or captcha page.
+type Target struct {
+	Name      string   `json:"name"` // defaults to the URL host
+	URL       string   `json:"url"`
+	Status    []int    `json:"status,omitempty"`     // accepted status codes; default any 2xx
+	Body      string   `json:"body,omitempty"`       // substring the body must contain
+	BodyRegex string   `json:"body_regex,omitempty"` // regex the body must match
+	Pins      []string `json:"pins,omitempty"`       // "sha256/<base64>" public key pins for https:// chains
+
+	BodyRe *regexp.Regexp `json:"-"` // compiled BodyRegex, set by the loader
+}
+
+// LeakRule flags something a proxy added to or changed in the judge
+// request. Severity decides what a match does to the anonymity grade:
+// "high" makes the proxy transparent, "medium" anonymous, and "low" is
+// only reported.
+type LeakRule struct {
+	Header     string `json:"header,omitempty"`       // header name, any casing
+	Pattern    string `json:"pattern,omitempty"`      // case-insensitive value regex; empty matches any value
+	Order      bool   `json:"order,omitempty"`        // instead of a header: the headers we sent arrived reordered or re-cased
+	SkipExitIP bool   `json:"skip_exit_ip,omitempty"` // ignore values naming only the exit IP, as CDNs in front of the judge send
+	Severity   string `json:"severity"`               // low, medium or high
+	Reason     string `json:"reason,omitempty"`
+
+	PatternRe *regexp.Regexp `json:"-"` // compiled Pattern, set by the loader
+}
@@ -1,26 +1,193 @@
 package model
 
+import "time"
+
 // ProxyInput is a normalized representation of a proxy entry
 // parsed from file lines such as:
 //   ip:port
 //   ip:port:username:password
 //   username:password@ip:port
+//   socks5://username:password@ip:port
 type ProxyInput struct {
-    Host       string // IPv4 or hostname
+    Host       string // IPv4, IPv6 (without brackets) or hostname
     Port       int
     Username   string
     Password   string
-    Type       string // "http", "https", "socks5", "" if unknown at parse time
+    Type       string // "http", "https", "socks4", "socks4a", "socks5", "socks5h", "" if unknown at parse time
+    TLS        bool   // proxy endpoint itself speaks TLS (scheme+tls://); implied by Type "https"
     Raw        string // original line for debugging
 }
 
+// CertInfo summarizes a TLS certificate seen during a check.
+type CertInfo struct {
+    Subject  string
+    Issuer   string
+    NotAfter time.Time // expiry
+    Pin      string    // "sha256/<base64>" of the public key, usable with --tls-pin
+}
+
 // ProxyCapabilities describes what traffic appears allowed
 // through the proxy (to be filled later during checking).
 type ProxyCapabilities struct {
-    SMTP   bool // can connect TCP to smtp targets (e.g. port 587)
-    POP3   bool // can connect TCP to pop3 targets (110/995)
-    IMAP   bool // can connect TCP to imap targets (143/993)
-    UDP    bool // can relay UDP (SOCKS5 UDP ASSOCIATE)
+    Checked bool // the capability phase ran (--check-capabilities, proxy alive)
+    SMTP   bool // got a 220 greeting and EHLO reply from an smtp target (587/465)
+    SMTPBanner   string // 220 greeting line
+    SMTPPort     int    // port that succeeded
+    SMTPStartTLS bool   // EHLO advertised STARTTLS
+    POP3   bool // got a +OK greeting from a pop3 target (995/110)
+    POP3Banner   string
+    POP3Port     int
+    IMAP   bool // got a * OK greeting from an imap target (993/143)
+    IMAPBanner   string
+    IMAPPort     int
+    UDP    bool // relayed a DNS query/answer through SOCKS5 UDP ASSOCIATE
+    UDPLatencyMs int64  // UDP round-trip through the relay
+    UDPError     string // why UDP failed, e.g. "relay_unreachable", "relay_unroutable"
+}
+
+// TargetResult is one --targets site fetched through the proxy.
+type TargetResult struct {
+    Name           string
+    URL            string
+    Reachable      bool   // expected status and body, and not blocked
+    StatusCode     int
+    LatencyMs      int64
+    Blocked        bool   // captcha, anti-bot challenge or 403/429
+    BlockReason    string // e.g. "cloudflare challenge", "http 403"
+    TLSIntercepted bool   // https:// target answered with a certificate that isn't the site's
+    ObservedIssuer string // issuer of the leaf certificate received, https:// targets only
+    Error          string // why the target is not reachable
+}
+
+// DNSLeakResult is the DNS leak test: a name nobody has looked up before
+// is resolved through the proxy, and the zone's DNS stand-in on the judge
+// reports which resolvers asked for it.
+type DNSLeakResult struct {
+    Name      string        // unique name under --dns-leak-zone
+    Resolvers []DNSResolver // in the order they asked
+    Leak      bool          // a resolver is outside the exit IP's country
+    Error     string        // why no resolver could be seen
+}
+
+// DNSResolver is a resolver that asked the DNS stand-in for the test name.
+type DNSResolver struct {
+    IP      string
+    Country string
+    ASN     string
+    ISP     string
+}
+
+// AnonymityLeak is a leak rule that matched what the judge saw.
+type AnonymityLeak struct {
+    Header   string // as the judge reported it; "header order" for order anomalies
+    Value    string // header value, or how the order changed
+    Severity string // low, medium or high
+    Reason   string
+}
+
+
+// TargetStats is the batch-wide reachability of one --targets site.
+type TargetStats struct {
+    Name         string  `json:"name"`
+    Tested       int     `json:"tested"` // alive proxies that fetched it
+    Reachable    int     `json:"reachable"`
+    Blocked      int     `json:"blocked"`
+    ReachablePct float64 `json:"reachable_pct"`
+}
+
+// IntegrityResult is the content integrity check: a payload with a known
+// SHA-256 fetched through the proxy over plain HTTP and over HTTPS.
+type IntegrityResult struct {
+    HTTP  IntegrityFetch
+    HTTPS IntegrityFetch
+}
+
+// IntegrityFetch is one payload fetch and how it differed from the original.
+type IntegrityFetch struct {
+    URL        string
+    StatusCode int
+    SHA256     string   // hex SHA-256 of the body received
+    Diff       []string // e.g. "injected <script>", "header X-Frame-Options stripped"; empty if intact
+    Error      string   // the fetch failed and nothing was compared
+}
+
+// RotationProfile describes how a rotating / backconnect gateway hands
+// out exit IPs, from repeated judge requests through the same endpoint.
+type RotationProfile struct {
+    Requests     int            // requests without a session ID, each on a new connection
+    Succeeded    int            // of those, how many reached the judge
+    ExitIPs      []string       // distinct exit IPs, in order of first appearance
+    RotationRate float64        // share of consecutive successful requests whose exit IP changed (0..1)
+    Countries    map[string]int // exit country -> successful requests
+    ASNs         map[string]int // exit ASN -> successful requests
+
+    SessionRequests  int   // requests with a fixed session ID in the username, 0 without credentials
+    StickyHeld       bool  // the session kept one exit IP for every session request
+    StickyDurationMs int64 // how long the session kept its first exit IP
+}
+
+// Timings breaks one judge request down into phases, in milliseconds.
+// A phase that did not happen (cleartext proxy, GET forwarding) is 0.
+type Timings struct {
+    ProxyConnectMs float64 // TCP connect to the proxy
+    ProxyTLSMs     float64 // TLS handshake with a TLS-wrapped proxy
+    TunnelMs       float64 // SOCKS / HTTP CONNECT handshake until the tunnel is up
+    TargetTLSMs    float64 // TLS handshake with the judge, through the tunnel
+    FirstByteMs    float64 // request written -> first response byte (proxy + upstream site)
+    GeoLookupMs    float64 // GeoIP lookup of the exit IP
+}
+
+// LatencySamples summarizes repeated judge requests through one proxy.
+type LatencySamples struct {
+    Attempts     int     // requests made (at most 2x --samples)
+    Succeeded    int     // requests that got a judge response
+    SuccessRatio float64 // Succeeded / Attempts
+    MinMs        float64
+    MedianMs     float64
+    P95Ms        float64
+    MaxMs        float64
+    JitterMs     float64 // mean absolute difference between consecutive samples
+}
+
+// ErrorClass categorizes why a check failed, so a batch can tell dead
+// proxies from wrong credentials or a blocked judge.
+type ErrorClass string
+
+const (
+    ErrorClassDNSFailure      ErrorClass = "dns_failure"         // proxy or target hostname did not resolve
+    ErrorClassConnectRefused  ErrorClass = "connect_refused"     // proxy (or the target behind it) refused the connection
+    ErrorClassConnectTimeout  ErrorClass = "connect_timeout"     // no answer within --timeout
+    ErrorClassAuthRequired    ErrorClass = "auth_required"       // proxy wants credentials we don't have
+    ErrorClassAuthFailed      ErrorClass = "auth_failed"         // proxy rejected our credentials
+    ErrorClassSOCKSRuleDenied ErrorClass = "socks_rule_denied"   // SOCKS server refused the request by rule
+    ErrorClassTLS             ErrorClass = "tls_error"           // TLS to the proxy or the judge failed
+    ErrorClassUpstreamHTTP    ErrorClass = "upstream_http_error" // unexpected HTTP status from the proxy or judge
+    ErrorClassJudgeParse      ErrorClass = "judge_parse_error"   // judge answered, but not with its JSON
+    ErrorClassGeoLookupFailed ErrorClass = "geo_lookup_failed"   // exit IP not in the GeoIP database; annotates Stages.Geo, never fails a check
+    ErrorClassOther           ErrorClass = "other"
+)
+
+// StageStatus is the outcome of one stage of a check.
+type StageStatus string
+
+const (
+    StageOK      StageStatus = "ok"
+    StageFailed  StageStatus = "failed"
+    StageSkipped StageStatus = "skipped" // an earlier stage failed
+)
+
+// StageResult is one stage's status and, if it failed, why.
+type StageResult struct {
+    Status StageStatus
+    Error  string
+}
+
+// Stages records the check pipeline stage by stage. Only Liveness decides
+// Alive; ExitIP and Geo failures annotate an otherwise alive result.
+type Stages struct {
+    Liveness StageResult // the proxy relayed a request and the judge answered
+    ExitIP   StageResult // the judge reported a usable exit IP
+    Geo      StageResult // GeoIP / ASN enrichment of the exit IP
 }
 
 // ProxyCheckResult is the final result for a single proxy
@@ -30,14 +197,42 @@ type ProxyCheckResult struct {
     Alive          bool
     StatusCode     int    // HTTP status (or 0 if not HTTP)
     LatencyMs      int64  // timeout
+    Timings        Timings // where LatencyMs went, for the judge request
+    Samples        *LatencySamples // --samples latency distribution, nil otherwise
     Country        string
     City           string
     ISP            string // provider / ASN name
     IP             string // external IP
     Anonymity      string // transparent / anonymous / elite
+    RealIPLeaks    []string // where the judge saw our real IP: "origin" and/or header names
+    AnonymityLeaks []AnonymityLeak // leak rules that matched: why Anonymity is not "elite"
     FraudScore     float64 // 0..100 heuristic
+    Tampered       bool    // the integrity payload came back modified (--integrity)
+    DNSLeak        *DNSLeakResult // resolvers the proxy used (--dns-leak-zone), nil if not tested
+    Integrity      *IntegrityResult // per-scheme integrity details, nil if not checked
 	Capabilities   ProxyCapabilities
+    CapabilityResults map[string]bool // capability matrix probe name -> passed
+    DownloadKBps   float64 // bandwidth test (--bandwidth-bytes), 0 if not run or failed
+    UploadKBps     float64
+    TTFBMs         int64   // time to first byte of the bandwidth download
+    Targets        []TargetResult // per-site results (--targets), in file order
+    Rotation       *RotationProfile // exit-IP rotation profile (--rotation), nil otherwise
+    DetectedProtocols []string // protocols the endpoint answered to (--type auto), best first
+    Software           string   // proxy product, e.g. "Squid", "MikroTik" (--fingerprint); "" if unknown
+    SoftwareVersion    string   // version, when the evidence carries one
+    SoftwareConfidence float64  // 0..1
+    SoftwareEvidence   []string // what gave it away, e.g. "request header: squid/4.10"
+    SupportsGET     bool   // HTTP proxy forwards absolute-form GET for http:// targets
+    SupportsCONNECT bool   // HTTP proxy tunnels via CONNECT (https:// targets)
+    ProxyCert      *CertInfo // certificate of a TLS-wrapped proxy endpoint, nil otherwise
+    TLSIntercepted bool       // the judge's HTTPS certificate was replaced on the way (re-signed by the proxy)
+    ObservedIssuer string     // issuer of the leaf certificate received for the judge over HTTPS
+    TLSChain       []CertInfo // certificate chain received for the judge over HTTPS, leaf first
+    IPv6Exit       bool   // proxy can egress to an IPv6-only endpoint
+    IPv6           string // external IPv6 seen by that endpoint
     Error          string // if failed
+    ErrorClass     ErrorClass // machine-readable category of Error
+    Stages         Stages // per-stage outcome: liveness, exit IP, geo enrichment
 
 	RawHeaders map[string]string // internal: headers observed by remote
 }
@@ -51,4 +246,14 @@ type BatchStats struct {
     AvgFraudScore             float64 `json:"avg_fraud_score"`
     TotalProcessingTimeMs     int64 `json:"total_processing_time_ms"`
     SuccessRatePct            float64 `json:"success_rate_pct"`
+    AvgDownloadKBps           float64 `json:"avg_download_kbps"`
+    AvgUploadKBps             float64 `json:"avg_upload_kbps"`
+    AvgTTFBMs                 float64 `json:"avg_ttfb_ms"`
+    ErrorClasses              map[ErrorClass]int `json:"error_classes"` // results per error class
+    Targets                   []TargetStats `json:"targets,omitempty"`   // per-site reachability (--targets)
+    TamperedProxies           int `json:"tampered_proxies"` // alive proxies that modified the integrity payload
+    TLSInterceptedProxies     int `json:"tls_intercepted_proxies"` // proxies that re-signed the judge's HTTPS certificate
+    DNSLeakProxies            int `json:"dns_leak_proxies"` // proxies resolving through a resolver outside the exit country
+    Software                  map[string]int `json:"software,omitempty"` // identified proxies per software (--fingerprint)
+    LeakHeaders               map[string]int `json:"leak_headers,omitempty"` // proxies per matched leak rule header
 }
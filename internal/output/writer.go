@@ -5,8 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/August26/proxycheck-go/internal/model"
 )
@@ -14,12 +19,21 @@ import (
 // PrintResultsTable prints a human-readable table of per-proxy results.
 func PrintResultsTable(w io.Writer, results []model.ProxyCheckResult) {
 	tw := tabwriter.NewWriter(w, 2, 4, 2, ' ', 0)
+	capNames := capabilityNames(results)
+	targetNames := targetNames(results)
 
 	// header
-	fmt.Fprintln(tw, "IP:PORT\tALIVE\tLAT(ms)\tCOUNTRY\tCITY\tISP\tANONYMITY\tFRAUD\tSTATUS\tSMTP\tPOP3\tIMAP\tUDP")
+	header := "IP:PORT\tALIVE\tLAT(ms)\tP50/P95\tJITTER\tOK%\tTTFB(ms)\tDL(KB/s)\tUL(KB/s)\tCOUNTRY\tCITY\tISP\tANONYMITY\tFRAUD\tTAMPER\tMITM\tDNSLEAK\tSOFTWARE\tSTATUS\tHTTP\tIPV6\tEXITS\tSMTP\tPOP3\tIMAP\tUDP"
+	for _, name := range capNames {
+		header += "\t" + strings.ToUpper(name)
+	}
+	for _, name := range targetNames {
+		header += "\t" + strings.ToUpper(name)
+	}
+	fmt.Fprintln(tw, header)
 
 	for _, r := range results {
-		hostport := fmt.Sprintf("%s:%d", r.Input.Host, r.Input.Port)
+		hostport := net.JoinHostPort(r.Input.Host, strconv.Itoa(r.Input.Port))
 
 		alive := "no"
 		if r.Alive {
@@ -31,6 +45,26 @@ func PrintResultsTable(w io.Writer, results []model.ProxyCheckResult) {
 			lat = fmt.Sprintf("%d", r.LatencyMs)
 		}
 
+		pcts, jit, okPct := "-", "-", "-"
+		if s := r.Samples; s != nil {
+			okPct = fmt.Sprintf("%.0f", s.SuccessRatio*100)
+			if s.Succeeded > 0 {
+				pcts = fmt.Sprintf("%.0f/%.0f", s.MedianMs, s.P95Ms)
+				jit = fmt.Sprintf("%.0f", s.JitterMs)
+			}
+		}
+
+		ttfb, down, up := "-", "-", "-"
+		if r.TTFBMs > 0 {
+			ttfb = fmt.Sprintf("%d", r.TTFBMs)
+		}
+		if r.DownloadKBps > 0 {
+			down = fmt.Sprintf("%.0f", r.DownloadKBps)
+		}
+		if r.UploadKBps > 0 {
+			up = fmt.Sprintf("%.0f", r.UploadKBps)
+		}
+
 		country := dashIfEmpty(r.Country)
 		city := dashIfEmpty(r.City)
 		isp := dashIfEmpty(r.ISP)
@@ -41,33 +75,78 @@ func PrintResultsTable(w io.Writer, results []model.ProxyCheckResult) {
 			fraud = fmt.Sprintf("%.1f", r.FraudScore)
 		}
 
+		tamper := "-"
+		if r.Integrity != nil {
+			tamper = boolToYN(r.Tampered)
+		}
+
+		mitm := "-"
+		if len(r.TLSChain) > 0 {
+			mitm = boolToYN(r.TLSIntercepted)
+		}
+
+		dnsLeak := "-"
+		if r.DNSLeak != nil && len(r.DNSLeak.Resolvers) > 0 {
+			dnsLeak = boolToYN(r.DNSLeak.Leak)
+		}
+
+		software := dashIfEmpty(strings.TrimSpace(r.Software + " " + r.SoftwareVersion))
+
 		status := "-"
 		if r.StatusCode > 0 {
 			status = fmt.Sprintf("%d", r.StatusCode)
+		} else if r.ErrorClass != "" {
+			status = string(r.ErrorClass)
 		} else if r.Error != "" {
 			status = r.Error
 		}
 
-		smtp := boolToYN(r.Capabilities.SMTP)
-		pop3 := boolToYN(r.Capabilities.POP3)
-		imap := boolToYN(r.Capabilities.IMAP)
-		udp := boolToYN(r.Capabilities.UDP)
+		httpModes := httpModes(r)
+		ipv6 := boolToYN(r.IPv6Exit)
+		exits := "-"
+		if r.Rotation != nil {
+			exits = fmt.Sprintf("%d/%d", len(r.Rotation.ExitIPs), r.Rotation.Succeeded)
+		}
+		smtp := capabilityYN(r, r.Capabilities.SMTP)
+		pop3 := capabilityYN(r, r.Capabilities.POP3)
+		imap := capabilityYN(r, r.Capabilities.IMAP)
+		udp := capabilityYN(r, r.Capabilities.UDP)
 
-		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+		cols := []string{
 			hostport,
 			alive,
 			lat,
+			pcts,
+			jit,
+			okPct,
+			ttfb,
+			down,
+			up,
 			country,
 			city,
 			isp,
 			anon,
 			fraud,
+			tamper,
+			mitm,
+			dnsLeak,
+			software,
 			status,
+			httpModes,
+			ipv6,
+			exits,
 			smtp,
 			pop3,
 			imap,
 			udp,
-		)
+		}
+		for _, name := range capNames {
+			cols = append(cols, capabilityCell(r, name))
+		}
+		for _, name := range targetNames {
+			cols = append(cols, targetCell(r, name))
+		}
+		fmt.Fprintln(tw, strings.Join(cols, "\t"))
 	}
 
 	tw.Flush()
@@ -82,6 +161,60 @@ func PrintSummary(w io.Writer, stats model.BatchStats) {
 	fmt.Fprintf(w, "  Alive proxies:            %d\n", stats.AliveProxies)
 	fmt.Fprintf(w, "  Avg latency (alive):      %.1f ms\n", stats.AvgLatencyMs)
 	fmt.Fprintf(w, "  Avg fraud score (alive):  %.1f\n", stats.AvgFraudScore)
+	if stats.AvgDownloadKBps > 0 || stats.AvgUploadKBps > 0 {
+		fmt.Fprintf(w, "  Avg TTFB:                 %.1f ms\n", stats.AvgTTFBMs)
+		fmt.Fprintf(w, "  Avg download:             %.1f KB/s\n", stats.AvgDownloadKBps)
+		fmt.Fprintf(w, "  Avg upload:               %.1f KB/s\n", stats.AvgUploadKBps)
+	}
+	if stats.TamperedProxies > 0 {
+		fmt.Fprintf(w, "  Tampering proxies:        %d\n", stats.TamperedProxies)
+	}
+	if stats.TLSInterceptedProxies > 0 {
+		fmt.Fprintf(w, "  TLS-intercepting proxies: %d\n", stats.TLSInterceptedProxies)
+	}
+	if stats.DNSLeakProxies > 0 {
+		fmt.Fprintf(w, "  DNS-leaking proxies:      %d\n", stats.DNSLeakProxies)
+	}
+	if len(stats.Software) > 0 {
+		fmt.Fprintln(w, "  Proxy software:")
+		names := make([]string, 0, len(stats.Software))
+		for name := range stats.Software {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "    %-24s%d\n", name+":", stats.Software[name])
+		}
+	}
+	if len(stats.LeakHeaders) > 0 {
+		fmt.Fprintln(w, "  Leak headers:")
+		names := make([]string, 0, len(stats.LeakHeaders))
+		for name := range stats.LeakHeaders {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "    %-24s%d\n", name+":", stats.LeakHeaders[name])
+		}
+	}
+	if len(stats.ErrorClasses) > 0 {
+		fmt.Fprintln(w, "  Errors by class:")
+		classes := make([]string, 0, len(stats.ErrorClasses))
+		for c := range stats.ErrorClasses {
+			classes = append(classes, string(c))
+		}
+		sort.Strings(classes)
+		for _, c := range classes {
+			fmt.Fprintf(w, "    %-24s%d\n", c+":", stats.ErrorClasses[model.ErrorClass(c)])
+		}
+	}
+	if len(stats.Targets) > 0 {
+		fmt.Fprintln(w, "  Target reachability:")
+		for _, t := range stats.Targets {
+			fmt.Fprintf(w, "    %-24s%d/%d (%.1f%%), %d blocked\n",
+				t.Name+":", t.Reachable, t.Tested, t.ReachablePct, t.Blocked)
+		}
+	}
 	fmt.Fprintf(w, "  Batch time:               %.2f s\n", float64(stats.TotalProcessingTimeMs)/1000.0)
 }
 
@@ -92,6 +225,142 @@ func dashIfEmpty(s string) string {
 	return s
 }
 
+// httpModes summarizes which HTTP proxy modes worked: "get", "connect",
+// "get+connect", or "-" (also for SOCKS proxies).
+func httpModes(r model.ProxyCheckResult) string {
+	switch {
+	case r.SupportsGET && r.SupportsCONNECT:
+		return "get+connect"
+	case r.SupportsGET:
+		return "get"
+	case r.SupportsCONNECT:
+		return "connect"
+	default:
+		return "-"
+	}
+}
+
+// capabilityYN renders a built-in capability flag, or "-" if the
+// capability phase did not run for this proxy.
+func capabilityYN(r model.ProxyCheckResult, ok bool) string {
+	if !r.Capabilities.Checked {
+		return "-"
+	}
+	return boolToYN(ok)
+}
+
+// capabilityNames returns the sorted capability matrix probe names seen in
+// any result; they become extra table/CSV columns.
+func capabilityNames(results []model.ProxyCheckResult) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, r := range results {
+		for name := range r.CapabilityResults {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// capabilityCell renders one matrix result: y/n, or "-" if the probe
+// did not run for this proxy.
+func capabilityCell(r model.ProxyCheckResult, name string) string {
+	ok, ran := r.CapabilityResults[name]
+	if !ran {
+		return "-"
+	}
+	return boolToYN(ok)
+}
+
+// targetNames returns the per-target check names in the order they were
+// configured (first seen in any result).
+func targetNames(results []model.ProxyCheckResult) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, r := range results {
+		for _, t := range r.Targets {
+			if !seen[t.Name] {
+				seen[t.Name] = true
+				names = append(names, t.Name)
+			}
+		}
+	}
+	return names
+}
+
+// findTarget returns r's result for the named target, or nil if the
+// target was not fetched through r.
+func findTarget(r model.ProxyCheckResult, name string) *model.TargetResult {
+	for i := range r.Targets {
+		if r.Targets[i].Name == name {
+			return &r.Targets[i]
+		}
+	}
+	return nil
+}
+
+// targetCell renders one target result: y, "blk" for a block page, n,
+// or "-" if the target was not fetched through this proxy.
+func targetCell(r model.ProxyCheckResult, name string) string {
+	t := findTarget(r, name)
+	switch {
+	case t == nil:
+		return "-"
+	case t.Blocked:
+		return "blk"
+	default:
+		return boolToYN(t.Reachable)
+	}
+}
+
+// integrityField renders one integrity fetch for CSV: its differences
+// joined with "|", or "error: ..." if the fetch failed.
+func integrityField(f model.IntegrityFetch) string {
+	if f.Error != "" {
+		return "error: " + f.Error
+	}
+	return strings.Join(f.Diff, "|")
+}
+
+// dnsResolversField renders DNS leak resolvers for CSV as
+// "ip country asn", joined with "|"; unknown geo is left out.
+func dnsResolversField(resolvers []model.DNSResolver) string {
+	parts := make([]string, 0, len(resolvers))
+	for _, r := range resolvers {
+		parts = append(parts, strings.Join(strings.Fields(r.IP+" "+r.Country+" "+r.ASN), " "))
+	}
+	return strings.Join(parts, "|")
+}
+
+// anonymityLeaksField renders matched leak rules for CSV as
+// "header:severity", joined with "|".
+func anonymityLeaksField(leaks []model.AnonymityLeak) string {
+	parts := make([]string, 0, len(leaks))
+	for _, leak := range leaks {
+		parts = append(parts, leak.Header+":"+leak.Severity)
+	}
+	return strings.Join(parts, "|")
+}
+
+// countsField renders a count map as "k:n|k:n", sorted by key.
+func countsField(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s:%d", k, counts[k]))
+	}
+	return strings.Join(parts, "|")
+}
+
 func boolToYN(b bool) string {
 	if b {
 		return "y"
@@ -135,6 +404,8 @@ func writeJSON(w io.Writer, results []model.ProxyCheckResult, stats model.BatchS
 func writeCSV(w io.Writer, results []model.ProxyCheckResult, stats model.BatchStats) error {
 	cw := csv.NewWriter(w)
 	defer cw.Flush()
+	capNames := capabilityNames(results)
+	targetNames := targetNames(results)
 
 	// header
 	header := []string{
@@ -142,41 +413,247 @@ func writeCSV(w io.Writer, results []model.ProxyCheckResult, stats model.BatchSt
 		"port",
 		"alive",
 		"latency_ms",
+		"proxy_connect_ms",
+		"proxy_tls_ms",
+		"tunnel_ms",
+		"target_tls_ms",
+		"first_byte_ms",
+		"geo_lookup_ms",
+		"sample_attempts",
+		"sample_successes",
+		"sample_success_ratio",
+		"latency_min_ms",
+		"latency_median_ms",
+		"latency_p95_ms",
+		"latency_max_ms",
+		"jitter_ms",
+		"ttfb_ms",
+		"download_kbps",
+		"upload_kbps",
 		"country",
 		"city",
 		"isp",
 		"ip",
 		"anonymity",
+		"real_ip_leaks",
+		"anonymity_leaks",
 		"fraud_score",
+		"tampered",
+		"integrity_http_diff",
+		"integrity_https_diff",
 		"status_code",
 		"error",
+		"error_class",
+		"liveness_status",
+		"exit_ip_status",
+		"exit_ip_error",
+		"geo_status",
+		"geo_error",
+		"detected_protocols",
+		"software",
+		"software_version",
+		"software_confidence",
+		"software_evidence",
+		"proxy_cert_subject",
+		"proxy_cert_issuer",
+		"proxy_cert_expiry",
+		"tls_intercepted",
+		"tls_observed_issuer",
+		"tls_chain_pins",
+		"dns_leak",
+		"dns_resolvers",
+		"dns_leak_error",
+		"supports_get",
+		"supports_connect",
+		"ipv6_exit",
+		"ipv6",
 		"smtp",
+		"smtp_port",
+		"smtp_starttls",
+		"smtp_banner",
 		"pop3",
+		"pop3_port",
+		"pop3_banner",
 		"imap",
+		"imap_port",
+		"imap_banner",
 		"udp",
+		"udp_latency_ms",
+		"udp_error",
+		"rotation_requests",
+		"rotation_exit_ips",
+		"rotation_rate",
+		"rotation_countries",
+		"rotation_asns",
+		"sticky_held",
+		"sticky_duration_ms",
+	}
+	for _, name := range capNames {
+		header = append(header, "cap_"+name)
+	}
+	for _, name := range targetNames {
+		prefix := "target_" + name + "_"
+		header = append(header, prefix+"reachable", prefix+"status", prefix+"latency_ms", prefix+"block_reason", prefix+"tls_intercepted", prefix+"error")
 	}
 	if err := cw.Write(header); err != nil {
 		return err
 	}
 
 	for _, r := range results {
+		var certSubject, certIssuer, certExpiry string
+		if r.ProxyCert != nil {
+			certSubject = r.ProxyCert.Subject
+			certIssuer = r.ProxyCert.Issuer
+			certExpiry = r.ProxyCert.NotAfter.UTC().Format(time.RFC3339)
+		}
+
+		var sAttempts, sOK, sRatio, sMin, sMedian, sP95, sMax, sJitter string
+		if s := r.Samples; s != nil {
+			sAttempts = fmt.Sprintf("%d", s.Attempts)
+			sOK = fmt.Sprintf("%d", s.Succeeded)
+			sRatio = fmt.Sprintf("%.2f", s.SuccessRatio)
+			sMin = fmt.Sprintf("%.1f", s.MinMs)
+			sMedian = fmt.Sprintf("%.1f", s.MedianMs)
+			sP95 = fmt.Sprintf("%.1f", s.P95Ms)
+			sMax = fmt.Sprintf("%.1f", s.MaxMs)
+			sJitter = fmt.Sprintf("%.1f", s.JitterMs)
+		}
+
+		var tlsIntercepted string
+		chainPins := make([]string, 0, len(r.TLSChain))
+		if len(r.TLSChain) > 0 {
+			tlsIntercepted = boolToYN(r.TLSIntercepted)
+		}
+		for _, c := range r.TLSChain {
+			chainPins = append(chainPins, c.Pin)
+		}
+
+		var dnsLeak, dnsResolvers, dnsError string
+		if dl := r.DNSLeak; dl != nil {
+			if len(dl.Resolvers) > 0 {
+				dnsLeak = boolToYN(dl.Leak)
+			}
+			dnsResolvers = dnsResolversField(dl.Resolvers)
+			dnsError = dl.Error
+		}
+
+		var tampered, httpDiff, httpsDiff string
+		if in := r.Integrity; in != nil {
+			tampered = boolToYN(r.Tampered)
+			httpDiff = integrityField(in.HTTP)
+			httpsDiff = integrityField(in.HTTPS)
+		}
+
+		var rotRequests, rotIPs, rotRate, rotCountries, rotASNs, stickyHeld, stickyMs string
+		if rot := r.Rotation; rot != nil {
+			rotRequests = fmt.Sprintf("%d/%d", rot.Succeeded, rot.Requests)
+			rotIPs = strings.Join(rot.ExitIPs, "|")
+			rotRate = fmt.Sprintf("%.2f", rot.RotationRate)
+			rotCountries = countsField(rot.Countries)
+			rotASNs = countsField(rot.ASNs)
+			if rot.SessionRequests > 0 {
+				stickyHeld = boolToYN(rot.StickyHeld)
+				stickyMs = fmt.Sprintf("%d", rot.StickyDurationMs)
+			}
+		}
+
 		row := []string{
 			r.Input.Host,
 			fmt.Sprintf("%d", r.Input.Port),
 			boolToYN(r.Alive),
 			fmt.Sprintf("%d", r.LatencyMs),
+			fmt.Sprintf("%.1f", r.Timings.ProxyConnectMs),
+			fmt.Sprintf("%.1f", r.Timings.ProxyTLSMs),
+			fmt.Sprintf("%.1f", r.Timings.TunnelMs),
+			fmt.Sprintf("%.1f", r.Timings.TargetTLSMs),
+			fmt.Sprintf("%.1f", r.Timings.FirstByteMs),
+			fmt.Sprintf("%.1f", r.Timings.GeoLookupMs),
+			sAttempts,
+			sOK,
+			sRatio,
+			sMin,
+			sMedian,
+			sP95,
+			sMax,
+			sJitter,
+			fmt.Sprintf("%d", r.TTFBMs),
+			fmt.Sprintf("%.1f", r.DownloadKBps),
+			fmt.Sprintf("%.1f", r.UploadKBps),
 			r.Country,
 			r.City,
 			r.ISP,
 			r.IP,
 			r.Anonymity,
+			strings.Join(r.RealIPLeaks, "|"),
+			anonymityLeaksField(r.AnonymityLeaks),
 			fmt.Sprintf("%.1f", r.FraudScore),
+			tampered,
+			httpDiff,
+			httpsDiff,
 			fmt.Sprintf("%d", r.StatusCode),
 			r.Error,
+			string(r.ErrorClass),
+			string(r.Stages.Liveness.Status),
+			string(r.Stages.ExitIP.Status),
+			r.Stages.ExitIP.Error,
+			string(r.Stages.Geo.Status),
+			r.Stages.Geo.Error,
+			strings.Join(r.DetectedProtocols, "|"),
+			r.Software,
+			r.SoftwareVersion,
+			fmt.Sprintf("%.2f", r.SoftwareConfidence),
+			strings.Join(r.SoftwareEvidence, "|"),
+			certSubject,
+			certIssuer,
+			certExpiry,
+			tlsIntercepted,
+			r.ObservedIssuer,
+			strings.Join(chainPins, "|"),
+			dnsLeak,
+			dnsResolvers,
+			dnsError,
+			boolToYN(r.SupportsGET),
+			boolToYN(r.SupportsCONNECT),
+			boolToYN(r.IPv6Exit),
+			r.IPv6,
 			boolToYN(r.Capabilities.SMTP),
+			fmt.Sprintf("%d", r.Capabilities.SMTPPort),
+			boolToYN(r.Capabilities.SMTPStartTLS),
+			r.Capabilities.SMTPBanner,
 			boolToYN(r.Capabilities.POP3),
+			fmt.Sprintf("%d", r.Capabilities.POP3Port),
+			r.Capabilities.POP3Banner,
 			boolToYN(r.Capabilities.IMAP),
+			fmt.Sprintf("%d", r.Capabilities.IMAPPort),
+			r.Capabilities.IMAPBanner,
 			boolToYN(r.Capabilities.UDP),
+			fmt.Sprintf("%d", r.Capabilities.UDPLatencyMs),
+			r.Capabilities.UDPError,
+			rotRequests,
+			rotIPs,
+			rotRate,
+			rotCountries,
+			rotASNs,
+			stickyHeld,
+			stickyMs,
+		}
+		for _, name := range capNames {
+			row = append(row, capabilityCell(r, name))
+		}
+		for _, name := range targetNames {
+			t := findTarget(r, name)
+			if t == nil {
+				row = append(row, "", "", "", "", "", "")
+				continue
+			}
+			row = append(row,
+				boolToYN(t.Reachable),
+				fmt.Sprintf("%d", t.StatusCode),
+				fmt.Sprintf("%d", t.LatencyMs),
+				t.BlockReason,
+				boolToYN(t.TLSIntercepted),
+				t.Error,
+			)
 		}
 		if err := cw.Write(row); err != nil {
 			return err
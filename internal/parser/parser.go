@@ -3,6 +3,8 @@ package parser
 import (
 	"bufio"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -15,6 +17,10 @@ import (
 //   ip:port
 //   ip:port:username:password
 //   username:password@ip:port
+//   scheme://[username:password@]ip:port
+//   scheme+tls://[username:password@]ip:port (TLS to the proxy itself)
+//
+// IPv6 addresses must be bracketed in every notation, e.g. [2001:db8::1]:1080.
 //
 // Empty lines and lines starting with '#' are ignored.
 func LoadFromFile(path string) ([]model.ProxyInput, error) {
@@ -55,8 +61,14 @@ func LoadFromFile(path string) ([]model.ProxyInput, error) {
 //   1. ip:port
 //   2. ip:port:user:pass
 //   3. user:pass@ip:port
+//   4. scheme://user:pass@ip:port (see proxySchemes)
 //
 func parseProxyLine(line string) (model.ProxyInput, error) {
+	// Case 0: URL form, the scheme decides the proxy type for this line
+	if strings.Contains(line, "://") {
+		return parseProxyURL(line)
+	}
+
 	// Case 1: username:password@ip:port
 	if strings.Contains(line, "@") {
 		parts := strings.SplitN(line, "@", 2)
@@ -90,7 +102,10 @@ func parseProxyLine(line string) (model.ProxyInput, error) {
 	// Could be:
 	//   ip:port
 	//   ip:port:user:pass
-	col := strings.Split(line, ":")
+	col, err := splitColumns(line)
+	if err != nil {
+		return model.ProxyInput{}, err
+	}
 
 	switch len(col) {
 	case 2:
@@ -132,6 +147,61 @@ func parseProxyLine(line string) (model.ProxyInput, error) {
 	}
 }
 
+// proxySchemes maps URL schemes accepted in proxy lines to ProxyInput.Type.
+var proxySchemes = map[string]string{
+	"http":    "http",
+	"https":   "https",
+	"socks4":  "socks4",
+	"socks4a": "socks4a",
+	"socks5":  "socks5",
+	"socks5h": "socks5h",
+}
+
+// parseProxyURL parses scheme://[user:pass@]host:port.
+// Credentials may be percent-encoded (e.g. "p%40ss" for "p@ss").
+// A "+tls" suffix on the scheme (e.g. "socks5+tls") marks a proxy whose
+// endpoint must be reached over TLS; "https" always is.
+func parseProxyURL(line string) (model.ProxyInput, error) {
+	u, err := url.Parse(line)
+	if err != nil {
+		return model.ProxyInput{}, fmt.Errorf("invalid proxy url %q: %w", line, err)
+	}
+
+	scheme, useTLS := strings.CutSuffix(strings.ToLower(u.Scheme), "+tls")
+	typ, ok := proxySchemes[scheme]
+	if !ok {
+		return model.ProxyInput{}, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Path != "" && u.Path != "/" {
+		return model.ProxyInput{}, fmt.Errorf("unexpected path in proxy url %q", line)
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return model.ProxyInput{}, fmt.Errorf("missing host in %q", line)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return model.ProxyInput{}, fmt.Errorf("invalid port in %q", line)
+	}
+
+	var user, pass string
+	if u.User != nil {
+		user = u.User.Username()
+		pass, _ = u.User.Password()
+	}
+
+	return model.ProxyInput{
+		Host:     host,
+		Port:     port,
+		Username: user,
+		Password: pass,
+		Type:     typ,
+		TLS:      useTLS,
+		Raw:      line,
+	}, nil
+}
+
 func splitUserPass(s string) (string, string, error) {
 	up := strings.SplitN(s, ":", 2)
 	if len(up) != 2 {
@@ -140,14 +210,37 @@ func splitUserPass(s string) (string, string, error) {
 	return up[0], up[1], nil
 }
 
-// splitHostPort handles host:port for IPv4 or hostname.
+// splitColumns splits a colon-separated line, keeping a leading
+// bracketed IPv6 host ("[2001:db8::1]:1080:user:pass") as one column
+// without its brackets.
+func splitColumns(line string) ([]string, error) {
+	if !strings.HasPrefix(line, "[") {
+		return strings.Split(line, ":"), nil
+	}
+	end := strings.Index(line, "]")
+	if end < 0 {
+		return nil, fmt.Errorf("unterminated ipv6 address in %q", line)
+	}
+	host := line[1:end]
+	if ip := net.ParseIP(host); ip == nil || ip.To4() != nil {
+		return nil, fmt.Errorf("invalid ipv6 address %q", host)
+	}
+	rest := line[end+1:]
+	if !strings.HasPrefix(rest, ":") {
+		return nil, fmt.Errorf("missing port in %q", line)
+	}
+	return append([]string{host}, strings.Split(rest[1:], ":")...), nil
+}
+
+// splitHostPort handles host:port for IPv4, hostname or bracketed IPv6.
 func splitHostPort(s string) (string, int, error) {
-	parts := strings.Split(s, ":")
-	if len(parts) != 2 {
+	host, portStr, err := net.SplitHostPort(s)
+	if err != nil {
 		return "", 0, fmt.Errorf("invalid host:port: %q", s)
 	}
-	host := parts[0]
-	portStr := parts[1]
+	if strings.Contains(host, ":") && net.ParseIP(host) == nil {
+		return "", 0, fmt.Errorf("invalid ipv6 address %q", host)
+	}
 
 	port, err := strconv.Atoi(portStr)
 	if err != nil {